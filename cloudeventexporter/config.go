@@ -2,17 +2,34 @@ package cloudeventexporter
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"unicode"
 
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
 )
 
 type Config struct {
-	Ce                            CloudEventSpec `mapstructure:"ce"`
-	Filter                        string         `mapstructure:"filter"`
+	Ce      CloudEventSpec           `mapstructure:"ce"`
+	Filter  string                   `mapstructure:"filter"`
+	Filters cloudevent.FiltersConfig `mapstructure:"filters"`
+	Mapping cloudevent.MappingConfig `mapstructure:"mapping"`
+	Mode    string                   `mapstructure:"mode"`   // binary (default) or structured HTTP content mode
+	Format  string                   `mapstructure:"format"` // json (default) or protobuf, format of the body in structured mode
+
+	// Masks secrets and PII in the data values before the events are encoded
+	Redaction cloudevent.RedactionConfig `mapstructure:"redaction"`
+
+	// Resource attributes (ex: k8s.cluster.name) copied in data or sent as extension headers of every event
+	ResourceAttributes []cloudevent.ResourceAttributeConfig `mapstructure:"resource_attributes"`
+
+	// What to do with a log which doesn't have all the mapped fields: fail (default), drop or default
+	OnMissingAttributes      string            `mapstructure:"on_missing_attributes"`
+	MissingAttributeDefaults map[string]string `mapstructure:"missing_attribute_defaults"` // keyed by mapped field
+
 	//Endpoint                      string         `mapstructure:"endpoint"`
 	confighttp.HTTPClientSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct.
 	exporterhelper.QueueSettings  `mapstructure:"sending_queue"`
@@ -20,9 +37,16 @@ type Config struct {
 }
 
 type CloudEventSpec struct {
-	SpecVersion string `mapstructure:"spec_version"`
-	AppendType  string `mapstructure:"append_type"`
-	Source      string `mapstructure:"source"`
+	SpecVersion     string `mapstructure:"spec_version"`
+	AppendType      string `mapstructure:"append_type"`
+	Source          string `mapstructure:"source"`
+	TypeTemplate    string `mapstructure:"type_template"`    // Go text/template which forms Ce-Type, see DEFAULT_TYPE_TEMPLATE
+	SubjectTemplate string `mapstructure:"subject_template"` // Go text/template which forms Ce-Subject, see DEFAULT_SUBJECT_TEMPLATE
+	DataSchema      string `mapstructure:"data_schema"`      // Ce-Dataschema (1.0) or Ce-Schemaurl (0.3), not sent when empty
+	TimeSource      string `mapstructure:"time_source"`      // mapping (default), timestamp or observed_timestamp
+	OnInvalidTime   string `mapstructure:"on_invalid_time"`  // omit (default), now or fail
+
+	Extensions []cloudevent.ExtensionConfig `mapstructure:"extensions"` // extension attributes sent as Ce-<name> headers
 }
 
 var _ component.Config = (*Config)(nil)
@@ -45,6 +69,70 @@ func (cfg *Config) Validate() error {
 		return errors.New("source field can not be empty")
 	}
 
+	if err := cloudevent.ValidateSpecVersion(cfg.Ce.SpecVersion); err != nil {
+		return err
+	}
+
+	if err := cloudevent.ValidateDataSchema(cfg.Ce.DataSchema); err != nil {
+		return err
+	}
+
+	if err := cloudevent.ValidateExtensions(cloudevent.WithResourceExtensions(cfg.Ce.Extensions, cfg.ResourceAttributes)); err != nil {
+		return err
+	}
+
+	if len(cfg.Ce.TypeTemplate) > 0 {
+		if _, err := cloudevent.ParseTemplate("type_template", cfg.Ce.TypeTemplate); err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.Ce.SubjectTemplate) > 0 {
+		if _, err := cloudevent.ParseTemplate("subject_template", cfg.Ce.SubjectTemplate); err != nil {
+			return err
+		}
+	}
+
+	if err := cloudevent.ValidateTimeConfig(cfg.Ce.TimeSource, cfg.Ce.OnInvalidTime); err != nil {
+		return err
+	}
+
+	if err := cfg.Filters.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Mapping.Validate(); err != nil {
+		return err
+	}
+
+	if err := cloudevent.ValidateResourceAttributes(cfg.ResourceAttributes, &cfg.Mapping); err != nil {
+		return err
+	}
+
+	if err := cfg.Redaction.Validate(); err != nil {
+		return err
+	}
+
+	switch cfg.Mode {
+	case "", MODE_BINARY, MODE_STRUCTURED:
+	default:
+		return fmt.Errorf("mode must be one of '%s' or '%s', provided: %s", MODE_BINARY, MODE_STRUCTURED, cfg.Mode)
+	}
+
+	if err := cloudevent.ValidateFormat(cfg.Format, cfg.Ce.SpecVersion); err != nil {
+		return err
+	}
+
+	// Only the data is in the body in binary mode
+	if cfg.Format == cloudevent.FORMAT_PROTOBUF && cfg.Mode != MODE_STRUCTURED {
+		return fmt.Errorf("format '%s' can only be used in '%s' mode", cloudevent.FORMAT_PROTOBUF, MODE_STRUCTURED)
+	}
+
+	// A log can't be exported without converting it, so passthrough isn't allowed
+	if err := cloudevent.ValidateMissingAttrPolicy(cfg.OnMissingAttributes, false); err != nil {
+		return err
+	}
+
 	// Check if the endpoint format is right
 	if cfg.Endpoint != "" {
 		_, err := url.Parse(cfg.Endpoint)
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/config/confighttp"
@@ -17,11 +18,21 @@ func TestLoadConfig(t *testing.T) {
 
 	cloudEventConfig := Config{
 		Ce: CloudEventSpec{
-			SpecVersion: "test_again",
+			SpecVersion: cloudevent.SPEC_VERSION_10,
 			AppendType:  "test_again_again",
 			Source:      "test_again_again_again",
 		},
 		Filter: "*",
+		Mapping: cloudevent.MappingConfig{
+			ID:         "body.id",
+			Subject:    "resource.k8s.object.name",
+			TypeSuffix: "k8s.event.reason",
+			Data: []cloudevent.DataFieldMapping{
+				{Key: "reason", From: "k8s.event.reason"},
+				{Key: "message", From: "body"},
+			},
+			IncludeAttributes: "attributes",
+		},
 		HTTPClientSettings: confighttp.HTTPClientSettings{Endpoint: "http://some_test_url.com:1234"},
 	}
 
@@ -33,4 +44,9 @@ func TestLoadConfig(t *testing.T) {
 	}
 
 	assert.Equal(t, unmarsheledConf, cloudEventConfig)
+
+	// The collector loads it over the defaults
+	cfg := CreateDefaultConfig().(*Config)
+	require.NoError(t, cm.Unmarshal(cfg))
+	assert.NoError(t, cfg.Validate())
 }
@@ -3,82 +3,103 @@ package cloudeventexporter
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"runtime"
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
 
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-)
-
-var (
-	filters        []string         // k8s.event.reason filters
-	filterAllowAll bool     = false // if configuration changes this to true, it'll let pass all of the logs
-
-	typeVersion string // typeverson will define the body type of CloudEvent (right now it's v1 specific)
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 const (
-	// Cloud-event body skeleton
-	CE_DATA_META_BODY = `{"reason":"%s","start_time":"%s","name":"%s","namespace":"%s","count":%d,"message":"%s"}`
-
 	// Cloud-event required headers
 	HEADER_CE_ID          = "Ce-Id"
 	HEADER_CE_TYPE        = "Ce-Type"
 	HEADER_CE_SOURCE      = "Ce-Source"
 	HEADER_CE_SPECVERSION = "Ce-Specversion"
+	HEADER_CE_SUBJECT     = "Ce-Subject"
+	HEADER_CE_TIME        = "Ce-Time"
+	HEADER_CE_TRACEPARENT = "Ce-Traceparent" // trace context of the log, traceparent header is the span of the request
 	HEADER_CONTENT_TYPE   = "Content-Type"
 
 	// Other required HTTP headers
-	HEADER_RETRY_AFTER = "Retry-After"
-	CONTENT_TYPE       = "application/json"
+	HEADER_RETRY_AFTER        = "Retry-After"
+	CONTENT_TYPE              = "application/json"
+	CONTENT_TYPE_OCTET_STREAM = "application/octet-stream" // bytes data from mapping.data_from
 
-	// Open-telemetry required resources to look for in logs
-	ATTR_EVENT_COUNT      = "k8s.event.count"
-	ATTR_EVENT_NAME       = "k8s.event.name"
-	ATTR_EVENT_NS         = "k8s.namespace.name"
-	ATTR_EVENT_REASON     = "k8s.event.reason"
-	ATTR_EVENT_START_TIME = "k8s.event.start_time"
-	ATTR_EVENT_UID        = "k8s.event.uid"
+	// Prefix of the CloudEvent attributes in HTTP binary mode, ex: `Ce-Dataschema`
+	HEADER_CE_PREFIX = "Ce-"
+
+	// HTTP content modes, binary sends the context attributes as Ce- headers and the data as the body
+	// while structured sends the whole CloudEvent (in the configured format) as the body
+	MODE_BINARY     = "binary"
+	MODE_STRUCTURED = "structured"
+
+	CONTENT_TYPE_STRUCTURED_JSON = "application/cloudevents+json; charset=UTF-8"
+
+	// Name of the span created for every request
+	SPAN_NAME_SEND = typeStr + "/send"
 
 	// Channel size and also the concurrent go thread counts which
 	// reads gets the cloud-event and sends HTTP request
 	CHAN_SZ = 2
 
 	// To avoid fetching attribute from OTel use FETCH_ATTR = false
-	FETCH_ATTR    = true
+	FETCH_ATTR = true
 
 	// Enable retry for failed messages
 	RETRY_ENABLED = false
 )
 
 type cloudeventTransformExporter struct {
-	config      *Config
-	client      *http.Client
-	logger      *zap.Logger
-	settings    component.TelemetrySettings
-	useragent   string
-	source      string
-	specversion string
-	ceChan      chan *cloudeventdata
+	config         *Config
+	filters        []string // k8s.event.reason filters
+	filterAllowAll bool
+	logFilter      *cloudevent.LogFilter // filters rules, nil if there aren't any
+	typeBuilder    *cloudevent.TypeBuilder
+	subjectBuilder *cloudevent.SubjectBuilder // nil if mapping.subject is set or subject_template is empty
+	client         *http.Client
+	logger         *zap.Logger
+	mapping        *cloudevent.Mapping
+	telemetry      *exporterTelemetry
+	redactor       *cloudevent.Redactor // nil if redaction doesn't have any detectors or rules
+	timeResolver   *cloudevent.TimeResolver
+	settings       component.TelemetrySettings
+	tracer         trace.Tracer
+	useragent      string
+	source         string
+	spec           *cloudevent.SpecVersion
+	dataSchemaHdr  string // Ce-Dataschema (1.0) or Ce-Schemaurl (0.3)
+	extensions     []cloudevent.Extension
+	extensionHdrs  []string // Ce-<name> header of every extension, in the same order
+	ceChan         chan *cloudeventdata
 }
 
+// Data and Attributes of the mapped fields are only valid till pushLogs returns
 type cloudeventdata struct {
-	count     int
-	message   string
-	name      string
-	namespace string
-	reason    string
-	startTime string
-	uid       string // This field will be converted and passed to cloudeventTransformExporter.id
+	cloudevent.Event
+	time        string
+	typ         string   // Ce-Type formed by type_template
+	body        []byte   // Encoded data which is sent as the HTTP body
+	contentType string   // Content-Type of the body
+	extensions  []string // Values of ce.extensions in the same order, empty ones aren't sent
+	traceparent string   // Trace context of the log, empty if it doesn't have one
+	spanContext trace.SpanContext
 }
 
 // Create new exporter.
@@ -90,9 +111,10 @@ func newExporter(cf component.Config, set exporter.CreateSettings) (*cloudeventT
 		return nil, err
 	}
 
-	if len(conf.Ce.SpecVersion) > 0 {
-		typeVersion = "v" + string(conf.Ce.SpecVersion[0])
-	}
+	// Every instance keeps its own filters so that multiple named instances don't affect each other
+	var filters []string
+	filterAllowAll := false // if configuration changes this to true, it'll let pass all of the logs
+	spec := cloudevent.GetSpecVersion(conf.Ce.SpecVersion)
 
 	if len(conf.Filter) > 0 {
 		filters = strings.Split(conf.Filter, "|")
@@ -109,17 +131,65 @@ func newExporter(cf component.Config, set exporter.CreateSettings) (*cloudeventT
 		}
 	}
 
+	typeBuilder, err := cloudevent.NewTypeBuilder(conf.Ce.TypeTemplate, conf.Ce.AppendType, spec.TypeVersion, conf.Ce.SpecVersion, conf.Ce.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	// A mapped subject takes precedence over the template
+	var subjectBuilder *cloudevent.SubjectBuilder
+	if len(conf.Mapping.Subject) == 0 {
+		if subjectBuilder, err = cloudevent.NewSubjectBuilder(conf.Ce.SubjectTemplate, typeBuilder); err != nil {
+			return nil, err
+		}
+	}
+
+	logFilter, err := cloudevent.NewLogFilter(&conf.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	telemetry, err := newExporterTelemetry(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := cloudevent.NewMapping(&conf.Mapping, conf.MissingAttributeDefaults)
+	if mapping.DataFrom == nil {
+		mapping.Resource = cloudevent.NewResourceDataFields(conf.ResourceAttributes)
+	}
+
+	extensions := cloudevent.NewExtensions(cloudevent.WithResourceExtensions(conf.Ce.Extensions, conf.ResourceAttributes))
+	extensionHdrs := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		extensionHdrs = append(extensionHdrs, http.CanonicalHeaderKey(HEADER_CE_PREFIX+ext.Name))
+	}
+
 	userAgent := fmt.Sprintf("%s/%s (%s/%s)",
 		set.BuildInfo.Description, set.BuildInfo.Version, runtime.GOOS, runtime.GOARCH)
 
 	// client construction is deferred to start
 	return &cloudeventTransformExporter{
-		config:    conf,
-		logger:    set.Logger,
-		useragent: userAgent,
-		source:    conf.Ce.Source,
-		ceChan:    make(chan *cloudeventdata, CHAN_SZ),
-		settings:  set.TelemetrySettings,
+		config:         conf,
+		filters:        filters,
+		filterAllowAll: filterAllowAll,
+		logFilter:      logFilter,
+		typeBuilder:    typeBuilder,
+		subjectBuilder: subjectBuilder,
+		logger:         set.Logger,
+		mapping:        mapping,
+		telemetry:      telemetry,
+		redactor:       cloudevent.NewRedactor(&conf.Redaction, telemetry.recordRedactions),
+		timeResolver:   cloudevent.NewTimeResolver(conf.Ce.TimeSource, conf.Ce.OnInvalidTime, mapping),
+		useragent:      userAgent,
+		source:         conf.Ce.Source,
+		spec:           spec,
+		dataSchemaHdr:  http.CanonicalHeaderKey(HEADER_CE_PREFIX + spec.DataSchemaAttr),
+		extensions:     extensions,
+		extensionHdrs:  extensionHdrs,
+		ceChan:         make(chan *cloudeventdata, CHAN_SZ),
+		settings:       set.TelemetrySettings,
+		tracer:         set.TelemetrySettings.TracerProvider.Tracer(typeStr),
 	}, nil
 }
 
@@ -145,30 +215,19 @@ func (e *cloudeventTransformExporter) shutdown(_ context.Context) error {
 	return nil
 }
 
+/*
+Converts the logs and sends them to the endpoint, nothing is sent if any of the logs fails so that the retry
+of the batch doesn't deliver the events which were sent before the failure again
+*/
 func (e *cloudeventTransformExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
-	// Body that can be re-used for all the messages present in loop
-	// to avoid extra allocation/s
-	var ce cloudeventdata
-
 	// Remove anything not required from logs
-	if !filterAllowAll {
+	if !e.filterAllowAll || e.logFilter != nil {
 		ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+			resource := rl.Resource()
+
 			rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
 				sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
-					reason, reasonOk := lr.Attributes().Get(ATTR_EVENT_REASON)
-					if !reasonOk {
-						return false
-					}
-
-					reasonFound := false
-					for _, r := range filters {
-						if r == reason.AsString() {
-							reasonFound = true
-							break
-						}
-					}
-
-					return !reasonFound
+					return !e.keepLogRecord(resource, lr)
 				})
 				return sl.LogRecords().Len() == 0
 			})
@@ -176,8 +235,10 @@ func (e *cloudeventTransformExporter) pushLogs(ctx context.Context, ld plog.Logs
 		})
 	}
 
-	// Convert the log/s
+	// Convert the log/s, they're sent only once all of them are converted
+	events := make([]*cloudeventdata, 0, ld.LogRecordCount())
 	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		resource := ld.ResourceLogs().At(i).Resource()
 		scopeLogs := ld.ResourceLogs().At(i).ScopeLogs()
 
 		for j := 0; j < scopeLogs.Len(); j++ {
@@ -185,167 +246,371 @@ func (e *cloudeventTransformExporter) pushLogs(ctx context.Context, ld plog.Logs
 			records := logRecord.LogRecords()
 
 			for k := 0; k < records.Len(); k++ {
-				//var cloudEventMetaData string
-				currentMessage := records.At(k).Body()
+				// Each message gets its own data as it's processed in parallel once sent to the channel
+				ce := &cloudeventdata{}
 
 				// Get all the required attributes
 				if FETCH_ATTR {
-					attrMap := records.At(k).Attributes()
-
 					// Check if the required things are present,
 					// if not fail at the earliest reporting missing things
-					eventCount, eventCountOk := attrMap.Get(ATTR_EVENT_COUNT)
-					eventName, eventNameOk := attrMap.Get(ATTR_EVENT_NAME)
-					eventNs, eventNsOk := attrMap.Get(ATTR_EVENT_NS)
-					eventUid, eventUidOk := attrMap.Get(ATTR_EVENT_UID)
-					reason, reasonOk := attrMap.Get(ATTR_EVENT_REASON)
-					startTime, startTimeOk := attrMap.Get(ATTR_EVENT_START_TIME)
+					missing := e.mapping.Extract(resource, records.At(k), &ce.Event)
 
-					anyError := !(reasonOk && startTimeOk && eventNameOk && eventUidOk && eventNsOk && eventCountOk)
+					if len(missing) > 0 {
+						policy := e.config.OnMissingAttributes
+						if policy == cloudevent.MISSING_ATTR_DROP || policy == cloudevent.MISSING_ATTR_DEFAULT {
+							e.telemetry.recordMissingAttributes(ctx, policy)
+						}
 
-					if anyError {
-						overAllErrStr := ""
+						// With default policy the missing fields already have their defaults
+						if policy == cloudevent.MISSING_ATTR_DROP {
+							continue
+						} else if policy != cloudevent.MISSING_ATTR_DEFAULT {
+							overAllErrStr := ""
+							for _, m := range missing {
+								overAllErrStr += "{" + m + "} "
+							}
 
-						if !reasonOk {
-							overAllErrStr += "{" + ATTR_EVENT_REASON + "} "
+							return errors.New(fmt.Sprintf("Couldn't find %sattributes in the log", overAllErrStr))
 						}
+					}
+				} else {
+					// Useful case for testing but this can be totally removed
+					// Though it can be utilized if expansion is required later
+					ce.ID = cloudevent.NewUUIDv4()
+					ce.TypeSuffix = "TestReason"
+				}
 
-						if !startTimeOk {
-							overAllErrStr += "{" + ATTR_EVENT_START_TIME + "} "
-						}
+				// Before anything is formed from the data, so that .Data of the templates has the redacted values
+				if e.redactor != nil {
+					e.redactor.RedactValues(ctx, ce.Data)
+					e.redactor.RedactValues(ctx, ce.Resource)
+					if len(e.mapping.AttributesKey) > 0 {
+						ce.Attributes = e.redactor.RedactMap(ctx, ce.Attributes)
+					}
+				}
 
-						if !eventNameOk {
-							overAllErrStr += "{" + ATTR_EVENT_NAME + "} "
-						}
+				var err error
+				if e.subjectBuilder != nil {
+					if ce.Subject, err = e.subjectBuilder.Build(e.mapping, resource, records.At(k), &ce.Event); err != nil {
+						return err
+					}
+				}
 
-						if !eventUidOk {
-							overAllErrStr += "{" + ATTR_EVENT_UID + "} "
-						}
+				if ce.typ, err = e.typeBuilder.Build(e.mapping, resource, records.At(k), &ce.Event); err != nil {
+					return err
+				}
 
-						if !eventNsOk {
-							overAllErrStr += "{" + ATTR_EVENT_NS + "} "
-						}
+				if ce.time, err = e.timeResolver.Resolve(resource, records.At(k)); err != nil {
+					return err
+				}
 
-						if !eventCountOk {
-							overAllErrStr += "{" + ATTR_EVENT_COUNT + "} "
-						}
+				ce.extensions = cloudevent.AppendExtensionValues(nil, e.extensions, resource, records.At(k))
+				ce.traceparent = cloudevent.TraceParent(records.At(k))
+				ce.spanContext = spanContext(records.At(k))
 
-						return errors.New(fmt.Sprintf("Couldn't find %sattributes in the log", overAllErrStr))
+				// Templates and extensions read the log as it is (ex: .Attr), so what they've formed is redacted too
+				if e.redactor != nil {
+					e.redactor.RedactStrings(ctx, &ce.ID, &ce.Subject, &ce.typ)
+					for i := range ce.extensions {
+						e.redactor.RedactStrings(ctx, &ce.extensions[i])
 					}
+				}
 
-					ce = cloudeventdata{
-						count:     int(eventCount.Int()),
-						message:   currentMessage.AsString(),
-						name:      eventName.AsString(),
-						namespace: eventNs.AsString(),
-						reason:    reason.AsString(),
-						startTime: startTime.AsString(),
-						uid:       eventUid.AsString(),
-					}
-				} else {
-					// Useful case for testing but this can be totally removed
-					// Though it can be utilized if expansion is required later
-					ce = cloudeventdata{
-						count:     0,
-						message:   currentMessage.AsString(),
-						name:      "name",
-						namespace: "ns",
-						reason:    "TestReason",
-						startTime: "",
-						uid:       "fhapohnea-afj-ajfa",
-					}
+				// Values are only valid till this function returns so encode the body here
+				ce.body = e.constructCloudEventDataBody(make([]byte, 0, 256), ce)
+				ce.contentType = CONTENT_TYPE
+				if e.mapping.DataFrom != nil && ce.Data[0].Type() == pcommon.ValueTypeBytes {
+					ce.contentType = CONTENT_TYPE_OCTET_STREAM
 				}
+				if e.config.Mode == MODE_STRUCTURED {
+					ce.body, ce.contentType = e.constructStructuredBody(ce)
+				}
+				ce.Data = nil
+				ce.Attributes = pcommon.Map{}
 
-				// Send the message to channel so that it can be processed in parallel
-				e.ceChan <- &ce
+				events = append(events, ce)
 			}
 		}
 	}
 
+	// Send the messages to channel so that they can be processed in parallel
+	for _, ce := range events {
+		e.ceChan <- ce
+	}
+
 	return nil
 }
 
+/*
+Checks the log against the `filter` reasons and the `filters` rules, both have to let it pass
+Logs which don't have k8s.event.reason aren't filtered by reasons
+*/
+func (e *cloudeventTransformExporter) keepLogRecord(resource pcommon.Resource, lr plog.LogRecord) bool {
+	if !e.filterAllowAll {
+		if reason, reasonOk := lr.Attributes().Get(cloudevent.ATTR_EVENT_REASON); reasonOk {
+			reasonFound := false
+			for _, r := range e.filters {
+				if r == reason.AsString() {
+					reasonFound = true
+					break
+				}
+			}
+
+			if !reasonFound {
+				return false
+			}
+		}
+	}
+
+	return e.logFilter == nil || e.logFilter.Keep(resource, lr)
+}
+
 func (e *cloudeventTransformExporter) exportMessage() {
 	for ce := range e.ceChan {
-		// Correct JSON message if it has quotes
-		msg := strings.ReplaceAll(ce.message, "\"", "\\\"")
-
-		// Prepare JSON body
-		json_body := fmt.Sprintf(CE_DATA_META_BODY,
-			ce.reason,
-			ce.startTime,
-			ce.name,
-			ce.namespace,
-			ce.count,
-			msg,
-		)
-
-		// Create new request body and configure it with required things
-		req, err := http.NewRequest(http.MethodPost, e.config.Endpoint, bytes.NewReader([]byte(json_body)))
-
-		if err != nil {
-			e.logger.Error(err.Error())
-			continue
-		}
+		e.sendMessage(ce)
+	}
+}
+
+// Sends one CloudEvent, failures are only logged as pushLogs has already returned
+func (e *cloudeventTransformExporter) sendMessage(ce *cloudeventdata) {
+	// The span of the request is a child of the log's trace context so that the consumer joins that trace
+	ctx := context.Background()
+	if ce.spanContext.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, ce.spanContext)
+	}
+	ctx, span := e.tracer.Start(ctx, SPAN_NAME_SEND, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	// Create new request body and configure it with required things
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint, bytes.NewReader(ce.body))
+
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		e.logger.Error(err.Error())
+		return
+	}
+
+	// Add all the required headers, they're in the body in structured mode
+	if e.config.Mode != MODE_STRUCTURED {
+		e.addBinaryHeaders(req.Header, ce)
+	}
+	req.Header.Add(HEADER_CONTENT_TYPE, ce.contentType)
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	res, err := e.client.Do(req)
+
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		e.logger.Error(err.Error())
+		return
+	}
+	defer res.Body.Close()
 
-		// Add all the required headers
-		req.Header.Add(HEADER_CE_ID, ce.uid)
-		req.Header.Add(HEADER_CE_TYPE, configureCeType(e.config.Ce.AppendType, ce.reason))
-		req.Header.Add(HEADER_CE_SOURCE, e.config.Ce.Source)
-		req.Header.Add(HEADER_CE_SPECVERSION, e.config.Ce.SpecVersion)
-		req.Header.Add(HEADER_CONTENT_TYPE, CONTENT_TYPE)
+	// Read till the end so that the connection is reused for the next requests
+	_, _ = io.Copy(io.Discard, res.Body)
 
-		res, err := e.client.Do(req)
+	// Check if the status code is acceptable and continue for next requests
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return
+	}
+
+	var formattedErr error = fmt.Errorf("error exporting items, request to %s responded with HTTP Status Code %d",
+		e.config.Endpoint, res.StatusCode)
+	span.SetStatus(codes.Error, formattedErr.Error())
 
-		if err != nil {
-			e.logger.Error(err.Error())
-			continue
+	// If enabled, retry for errors, otherwise print error and leave
+	if RETRY_ENABLED {
+		retryAfter := 0
+
+		// Check if the server is overwhelmed.
+		// See spec https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#otlphttp-throttling
+		isThrottleError := res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable
+		if val := res.Header.Get(HEADER_RETRY_AFTER); isThrottleError && val != "" {
+			if seconds, err2 := strconv.Atoi(val); err2 == nil {
+				retryAfter = seconds
+			}
 		}
+		err = exporterhelper.NewThrottleRetry(formattedErr, time.Duration(retryAfter)*time.Second)
+		e.logger.Error(err.Error())
+		return
+	}
+
+	e.logger.Error(formattedErr.Error())
+}
 
-		// Check if the status code is acceptable and continue for next requests
-		if res.StatusCode >= 200 && res.StatusCode <= 299 {
-			continue
+// Context attributes of the CloudEvent as Ce- headers in binary mode
+func (e *cloudeventTransformExporter) addBinaryHeaders(header http.Header, ce *cloudeventdata) {
+	header.Add(HEADER_CE_ID, ce.ID)
+	header.Add(HEADER_CE_TYPE, ce.typ)
+	header.Add(HEADER_CE_SOURCE, e.config.Ce.Source)
+	header.Add(HEADER_CE_SPECVERSION, e.spec.Version)
+	if len(ce.Subject) > 0 {
+		header.Add(HEADER_CE_SUBJECT, ce.Subject)
+	}
+	if len(ce.time) > 0 {
+		header.Add(HEADER_CE_TIME, ce.time)
+	}
+	if len(e.config.Ce.DataSchema) > 0 {
+		header.Add(e.dataSchemaHdr, e.config.Ce.DataSchema)
+	}
+	for i, val := range ce.extensions {
+		if len(val) > 0 {
+			header.Add(e.extensionHdrs[i], val)
 		}
+	}
+	if len(ce.traceparent) > 0 {
+		header.Add(HEADER_CE_TRACEPARENT, ce.traceparent)
+	}
+}
+
+/*
+Wraps the encoded data (ce.body) in the structured CloudEvent of the configured format, the context attributes
+are in the body then instead of the headers. Returns the new body and its Content-Type
+*/
+func (e *cloudeventTransformExporter) constructStructuredBody(ce *cloudeventdata) ([]byte, string) {
+	retSlice := make([]byte, 0, len(ce.body)+256)
+	if e.config.Format == cloudevent.FORMAT_PROTOBUF {
+		return e.constructCloudEventProtoBody(retSlice, ce), cloudevent.CONTENT_TYPE_PROTOBUF
+	}
+	return e.constructCloudEventJsonBody(retSlice, ce), CONTENT_TYPE_STRUCTURED_JSON
+}
+
+/*
+Constructs the structured JSON CloudEvent, data is embedded as it is and bytes are base64 encoded under the attribute
+of the spec version
+Ex: {"datacontenttype":"application/json","id":"...","source":"...","specversion":"1.0","type":"...","data":{...}}
+*/
+func (e *cloudeventTransformExporter) constructCloudEventJsonBody(retSlice []byte, ce *cloudeventdata) []byte {
+	bytesData := ce.contentType == CONTENT_TYPE_OCTET_STREAM
+
+	retSlice = append(retSlice, cloudevent.OPEN_BRACE_BYTE)
+	retSlice = cloudevent.AppendJsonObjStr("datacontenttype", ce.contentType, retSlice)
+	if bytesData && len(e.spec.DataEncodingAttr) > 0 {
+		retSlice = cloudevent.AppendJsonObjStr(e.spec.DataEncodingAttr, cloudevent.DATA_ENCODING_BASE64, append(retSlice, cloudevent.COMMA_BYTE))
+	}
+	retSlice = cloudevent.AppendJsonObjStr("id", ce.ID, append(retSlice, cloudevent.COMMA_BYTE))
+	retSlice = cloudevent.AppendJsonObjStr("source", e.config.Ce.Source, append(retSlice, cloudevent.COMMA_BYTE))
+	retSlice = cloudevent.AppendJsonObjStr("specversion", e.spec.Version, append(retSlice, cloudevent.COMMA_BYTE))
+	retSlice = cloudevent.AppendJsonObjStr("type", ce.typ, append(retSlice, cloudevent.COMMA_BYTE))
+	if len(ce.Subject) > 0 {
+		retSlice = cloudevent.AppendJsonObjStr("subject", ce.Subject, append(retSlice, cloudevent.COMMA_BYTE))
+	}
+	if len(ce.time) > 0 {
+		retSlice = cloudevent.AppendJsonObjStr("time", ce.time, append(retSlice, cloudevent.COMMA_BYTE))
+	}
+	if len(e.config.Ce.DataSchema) > 0 {
+		retSlice = cloudevent.AppendJsonObjStr(e.spec.DataSchemaAttr, e.config.Ce.DataSchema, append(retSlice, cloudevent.COMMA_BYTE))
+	}
+	for i, val := range ce.extensions {
+		if len(val) > 0 {
+			retSlice = cloudevent.AppendJsonObjStr(e.extensions[i].Name, val, append(retSlice, cloudevent.COMMA_BYTE))
+		}
+	}
+	if len(ce.traceparent) > 0 {
+		retSlice = cloudevent.AppendJsonObjStr(cloudevent.EXTENSION_TRACEPARENT, ce.traceparent, append(retSlice, cloudevent.COMMA_BYTE))
+	}
 
-		var formattedErr error = fmt.Errorf("error exporting items, request to %s responded with HTTP Status Code %d",
-			e.config.Endpoint, res.StatusCode)
+	if bytesData {
+		retSlice = cloudevent.AppendJsonObjStr(e.spec.DataBase64Attr, base64.StdEncoding.EncodeToString(ce.body), append(retSlice, cloudevent.COMMA_BYTE))
+	} else {
+		retSlice = cloudevent.AppendJsonObjElse("data", ce.body, append(retSlice, cloudevent.COMMA_BYTE))
+	}
 
-		// If enabled, retry for errors, otherwise print error and leave
-		if RETRY_ENABLED {
-			retryAfter := 0
+	return append(retSlice, cloudevent.CLOSE_BRACE_BYTE)
+}
 
-			// Check if the server is overwhelmed.
-			// See spec https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#otlphttp-throttling
-			isThrottleError := res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable
-			if val := res.Header.Get(HEADER_RETRY_AFTER); isThrottleError && val != "" {
-				if seconds, err2 := strconv.Atoi(val); err2 == nil {
-					retryAfter = seconds
-				}
-			}
-			err = exporterhelper.NewThrottleRetry(formattedErr, time.Duration(retryAfter)*time.Second)
-			e.logger.Error(err.Error())
-			continue
+/*
+Constructs the CloudEvent message of the spec's cloudevents.proto, attributes other than id, source, specversion
+and type go in the attributes map and data is text_data (binary_data for bytes)
+*/
+func (e *cloudeventTransformExporter) constructCloudEventProtoBody(retSlice []byte, ce *cloudeventdata) []byte {
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_ID, ce.ID)
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_SOURCE, e.config.Ce.Source)
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_SPEC_VERSION, e.spec.Version)
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_TYPE, ce.typ)
+
+	retSlice = cloudevent.AppendProtoAttribute(retSlice, "datacontenttype", cloudevent.PROTO_FIELD_CE_STRING, ce.contentType)
+	if len(ce.Subject) > 0 {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, "subject", cloudevent.PROTO_FIELD_CE_STRING, ce.Subject)
+	}
+	if len(ce.time) > 0 {
+		retSlice = cloudevent.AppendProtoTimeAttribute(retSlice, "time", ce.time)
+	}
+	if len(e.config.Ce.DataSchema) > 0 {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, e.spec.DataSchemaAttr, cloudevent.PROTO_FIELD_CE_URI, e.config.Ce.DataSchema)
+	}
+	for i, val := range ce.extensions {
+		if len(val) > 0 {
+			retSlice = cloudevent.AppendProtoAttribute(retSlice, e.extensions[i].Name, cloudevent.PROTO_FIELD_CE_STRING, val)
 		}
+	}
+	if len(ce.traceparent) > 0 {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, cloudevent.EXTENSION_TRACEPARENT, cloudevent.PROTO_FIELD_CE_STRING, ce.traceparent)
+	}
 
-		e.logger.Error(formattedErr.Error())
+	if ce.contentType == CONTENT_TYPE_OCTET_STREAM {
+		retSlice = protowire.AppendTag(retSlice, cloudevent.PROTO_FIELD_BINARY_DATA, protowire.BytesType)
+		return protowire.AppendBytes(retSlice, ce.body)
 	}
+
+	retSlice = protowire.AppendTag(retSlice, cloudevent.PROTO_FIELD_TEXT_DATA, protowire.BytesType)
+	start := len(retSlice)
+	return cloudevent.InsertProtoLength(cloudevent.ValidUTF8After(append(retSlice, ce.body...), start), start)
 }
 
-// Configures Ce-Type header's value, using the given reason (removes any spaces present)
-func configureCeType(pretext string, reason string) string {
-	var ret strings.Builder
-	ret.Grow(len(pretext) + len(reason))
+/*
+Constructs the JSON object which is sent as HTTP body, keys are the ones configured in mapping.data
+Ex: {"reason":"Created","start_time":"...","name":"...","namespace":"...","count":1,"message":"..."}
+With mapping.data_from the value of that field is the body, bytes are sent as they are
+*/
+func (e *cloudeventTransformExporter) constructCloudEventDataBody(retSlice []byte, ce *cloudeventdata) []byte {
+	if field := e.mapping.DataFrom; field != nil {
+		val := ce.Data[0]
+		switch {
+		case val.Type() == pcommon.ValueTypeBytes:
+			return append(retSlice, val.Bytes().AsRaw()...)
+		case field.JSON && val.Type() == pcommon.ValueTypeStr:
+			return cloudevent.AppendJsonEmbedded(val.Str(), retSlice)
+		}
+		return cloudevent.AppendJsonValue(val, retSlice)
+	}
 
-	ret.WriteString(pretext)
-	ret.WriteRune('.')
-	ret.WriteString(typeVersion) // It'll define the version
-	ret.WriteRune('.')
+	retSlice = append(retSlice, cloudevent.OPEN_BRACE_BYTE)
+	for i, val := range ce.Data {
+		if i > 0 {
+			retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+		}
 
-	for _, ch := range reason {
-		if !unicode.IsSpace(ch) {
-			ret.WriteRune(ch)
+		field := &e.mapping.Data[i]
+		if field.JSON && val.Type() == pcommon.ValueTypeStr {
+			retSlice = cloudevent.AppendJsonEmbedded(val.Str(), cloudevent.AppendJsonObjElse(field.Key, nil, retSlice))
+		} else {
+			retSlice = cloudevent.AppendJsonObjValue(field.Key, val, retSlice)
+		}
+	}
+	if len(e.mapping.AttributesKey) > 0 {
+		if len(ce.Data) > 0 {
+			retSlice = append(retSlice, cloudevent.COMMA_BYTE)
 		}
+		retSlice = cloudevent.AppendJsonMap(ce.Attributes, cloudevent.AppendJsonObjElse(e.mapping.AttributesKey, nil, retSlice))
+	}
+	hasKeys := len(ce.Data) > 0 || len(e.mapping.AttributesKey) > 0
+	retSlice = cloudevent.AppendResourceJson(retSlice, e.mapping.Resource, ce.Resource, hasKeys)
+	retSlice = append(retSlice, cloudevent.CLOSE_BRACE_BYTE)
+
+	return retSlice
+}
+
+// Trace context of the log record as a remote parent, invalid if the record doesn't have one
+func spanContext(lr plog.LogRecord) trace.SpanContext {
+	var flags trace.TraceFlags
+	if lr.Flags().IsSampled() {
+		flags = trace.FlagsSampled
 	}
 
-	return ret.String()
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID(lr.TraceID()),
+		SpanID:     trace.SpanID(lr.SpanID()),
+		TraceFlags: flags,
+		Remote:     true,
+	})
 }
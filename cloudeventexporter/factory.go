@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/exporter"
@@ -30,8 +31,20 @@ func NewFactory() exporter.Factory {
 func CreateDefaultConfig() component.Config {
 	return &Config{
 		Ce: CloudEventSpec{
-			SpecVersion: "1.0",
+			SpecVersion:     "1.0",
+			TypeTemplate:    cloudevent.DEFAULT_TYPE_TEMPLATE,
+			SubjectTemplate: cloudevent.DEFAULT_SUBJECT_TEMPLATE,
+			TimeSource:      cloudevent.TIME_SOURCE_MAPPING,
+			OnInvalidTime:   cloudevent.INVALID_TIME_OMIT,
 		},
+		Mapping: cloudevent.MappingConfig{
+			ID:         cloudevent.ATTR_EVENT_UID,
+			Time:       cloudevent.ATTR_EVENT_START_TIME,
+			TypeSuffix: cloudevent.ATTR_EVENT_REASON,
+
+			ParseJSONBody: true,
+		},
+		OnMissingAttributes: cloudevent.MISSING_ATTR_FAIL,
 	}
 }
 
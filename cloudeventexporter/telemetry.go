@@ -0,0 +1,57 @@
+package cloudeventexporter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/instrument"
+)
+
+const (
+	// Name of the meter and the prefix of the metrics this exporter reports
+	METER_NAME    = "github.com/hv/akash.chandra/cloudeventexporter"
+	METRIC_PREFIX = "exporter_" + typeStr + "_"
+
+	METRIC_ATTR_POLICY = "policy"
+	METRIC_ATTR_RULE   = "rule"
+)
+
+// Metrics reported by the exporter, attribute values are always from a fixed set to keep cardinality bounded
+type exporterTelemetry struct {
+	missingAttributes instrument.Int64Counter
+	redactions        instrument.Int64Counter
+}
+
+func newExporterTelemetry(set component.TelemetrySettings) (*exporterTelemetry, error) {
+	meter := set.MeterProvider.Meter(METER_NAME)
+
+	missingAttributes, err := meter.Int64Counter(
+		METRIC_PREFIX+"records_missing_attributes",
+		instrument.WithDescription("Number of log records which didn't have all the mapped fields, by on_missing_attributes policy"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	redactions, err := meter.Int64Counter(
+		METRIC_PREFIX+"redactions",
+		instrument.WithDescription("Number of matches redacted in the event data, by redaction detector or rule name"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exporterTelemetry{
+		missingAttributes: missingAttributes,
+		redactions:        redactions,
+	}, nil
+}
+
+func (t *exporterTelemetry) recordMissingAttributes(ctx context.Context, policy string) {
+	t.missingAttributes.Add(ctx, 1, attribute.String(METRIC_ATTR_POLICY, policy))
+}
+
+func (t *exporterTelemetry) recordRedactions(ctx context.Context, rule string, count int64) {
+	t.redactions.Add(ctx, count, attribute.String(METRIC_ATTR_RULE, rule))
+}
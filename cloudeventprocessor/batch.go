@@ -0,0 +1,114 @@
+package cloudeventtransform
+
+import (
+	"fmt"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// Content type of a JSON array of CloudEvents in structured mode
+	CONTENT_TYPE_BATCH = "application/cloudevents-batch+json; charset=utf-8"
+)
+
+// BatchConfig limits the events which are collapsed into one record in batch mode, 0 means no limit
+type BatchConfig struct {
+	MaxEvents int `mapstructure:"max_events"`
+	MaxBytes  int `mapstructure:"max_bytes"` // size of the JSON array (or CloudEventBatch), an event bigger than this is sent alone
+}
+
+/*
+eventBatch collects the converted records of one ScopeLogs, the first record of a batch (head) gets the
+JSON array as its body and only the content type as its attributes, the others are removed. With the protobuf
+format the body is a CloudEventBatch message, which is the events one after the other as its repeated field
+*/
+type eventBatch struct {
+	maxEvents int
+	maxBytes  int
+	protobuf  bool
+	head      plog.LogRecord
+	events    int
+	body      []byte
+}
+
+// Validate checks the batch limits
+func (cfg *BatchConfig) Validate() error {
+	if cfg.MaxEvents < 0 {
+		return fmt.Errorf("batch.max_events can not be negative, provided: %d", cfg.MaxEvents)
+	}
+
+	if cfg.MaxBytes < 0 {
+		return fmt.Errorf("batch.max_bytes can not be negative, provided: %d", cfg.MaxBytes)
+	}
+
+	return nil
+}
+
+func newEventBatch(cfg *BatchConfig, format string) *eventBatch {
+	return &eventBatch{maxEvents: cfg.MaxEvents, maxBytes: cfg.MaxBytes, protobuf: format == cloudevent.FORMAT_PROTOBUF}
+}
+
+// Adds the structured CloudEvent of the record, returns true if the record has to be removed as it's part of the head now
+func (b *eventBatch) add(lr plog.LogRecord, event []byte) bool {
+	if b.events > 0 {
+		full := b.maxEvents > 0 && b.events >= b.maxEvents
+		full = full || (b.maxBytes > 0 && len(b.body)+b.eventSize(event) > b.maxBytes)
+		if full {
+			b.flush()
+		}
+	}
+
+	if b.events == 0 {
+		b.head = lr
+		b.body = b.body[:0]
+		if !b.protobuf {
+			b.body = append(b.body, cloudevent.OPEN_BRACKET_BYTE)
+		}
+	} else if !b.protobuf {
+		b.body = append(b.body, cloudevent.COMMA_BYTE)
+	}
+
+	if b.protobuf {
+		b.body = protowire.AppendTag(b.body, cloudevent.PROTO_FIELD_BATCH_EVENTS, protowire.BytesType)
+		b.body = protowire.AppendBytes(b.body, event)
+	} else {
+		b.body = append(b.body, event...)
+	}
+	b.events++
+
+	return b.events > 1
+}
+
+// Bytes the event adds to the body of a batch which has events, comma before it and the closing bracket in JSON
+func (b *eventBatch) eventSize(event []byte) int {
+	if b.protobuf {
+		return protowire.SizeTag(cloudevent.PROTO_FIELD_BATCH_EVENTS) + protowire.SizeBytes(len(event))
+	}
+	return len(event) + 2
+}
+
+// Writes the collected events in the body of the head, the batch is empty afterwards
+func (b *eventBatch) flush() {
+	if b.events == 0 {
+		return
+	}
+
+	contentType := cloudevent.CONTENT_TYPE_BATCH_PROTOBUF
+	if !b.protobuf {
+		b.body = append(b.body, cloudevent.CLOSE_BRACKET_BYTE)
+		contentType = CONTENT_TYPE_BATCH
+	}
+
+	body := b.head.Body().SetEmptyBytes()
+	body.EnsureCapacity(len(b.body))
+	body.Append(b.body...)
+
+	// Attributes of the first event (ex: its partition key) don't apply to the whole batch
+	b.head.Attributes().Clear()
+	b.head.Attributes().PutStr(ATTR_CONTENT_TYPE, contentType)
+
+	b.events = 0
+	b.head = plog.LogRecord{}
+}
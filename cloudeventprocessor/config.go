@@ -2,26 +2,77 @@ package cloudeventtransform
 
 import (
 	"errors"
+	"fmt"
 	"unicode"
 
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
 	"go.opentelemetry.io/collector/component"
 )
 
 type Config struct {
-	Ce     CloudEventSpec `mapstructure:"ce"`
-	Filter string         `mapstructure:"filter"`
+	Ce      CloudEventSpec           `mapstructure:"ce"`
+	Filter  string                   `mapstructure:"filter"`
+	Filters cloudevent.FiltersConfig `mapstructure:"filters"`
+	Mode    string                   `mapstructure:"mode"`   // structured (default), binary or batch content mode
+	Batch   BatchConfig              `mapstructure:"batch"`  // limits of a batch in batch mode
+	Format  string                   `mapstructure:"format"` // json (default) or protobuf, format of the structured events
+	Mapping cloudevent.MappingConfig `mapstructure:"mapping"`
+	Traces  TracesConfig             `mapstructure:"traces"`  // spans converted in a traces pipeline
+	Metrics MetricsConfig            `mapstructure:"metrics"` // rules evaluated in a metrics pipeline
+	Output  OutputConfig             `mapstructure:"output"`  // where the events formed from traces and metrics are sent
+	Dedup   DedupConfig              `mapstructure:"dedup"`   // drops or tags the logs which were already seen
+
+	// Masks secrets and PII in the data values before the events are encoded
+	Redaction cloudevent.RedactionConfig `mapstructure:"redaction"`
+
+	// Resource attributes (ex: k8s.cluster.name) copied in data or in extensions of every event
+	ResourceAttributes []cloudevent.ResourceAttributeConfig `mapstructure:"resource_attributes"`
+
+	// encode (default) converts the logs to CloudEvents, decode converts structured CloudEvents back to logs
+	Direction     string `mapstructure:"direction"`
+	OnDecodeError string `mapstructure:"on_decode_error"` // fail, drop or passthrough (default)
+
+	// Log attribute which gets the partition key as well, so that exporters can use it as the message key
+	PartitionKeyAttribute string `mapstructure:"partition_key_attribute"`
+
+	// What to do with a log which doesn't have all the mapped fields: fail (default), drop, passthrough or default
+	OnMissingAttributes      string            `mapstructure:"on_missing_attributes"`
+	MissingAttributeDefaults map[string]string `mapstructure:"missing_attribute_defaults"` // keyed by mapped field
 }
 
 type CloudEventSpec struct {
-	SpecVersion string `mapstructure:"spec_version"`
-	AppendType  string `mapstructure:"append_type"`
-	Source      string `mapstructure:"source"`
+	SpecVersion     string `mapstructure:"spec_version"`
+	AppendType      string `mapstructure:"append_type"`
+	Source          string `mapstructure:"source"`
+	TypeTemplate    string `mapstructure:"type_template"`    // Go text/template which forms the type, see DEFAULT_TYPE_TEMPLATE
+	SubjectTemplate string `mapstructure:"subject_template"` // Go text/template which forms the subject, see DEFAULT_SUBJECT_TEMPLATE
+	DataSchema      string `mapstructure:"data_schema"`      // dataschema (1.0) or schemaurl (0.3), left out when empty
+
+	// Go text/template which forms the partitionkey extension, see DEFAULT_PARTITION_KEY_TEMPLATE
+	PartitionKeyTemplate string `mapstructure:"partition_key_template"`
+
+	TimeSource    string `mapstructure:"time_source"`     // mapping (default), timestamp or observed_timestamp
+	OnInvalidTime string `mapstructure:"on_invalid_time"` // omit (default), now or fail
+
+	Extensions []cloudevent.ExtensionConfig `mapstructure:"extensions"` // extension attributes added to every event
 }
 
 var _ component.Config = (*Config)(nil)
 
 // Validate checks if the processor configuration is valid
 func (cfg *Config) Validate() error {
+	if err := validateDirection(cfg.Direction, cfg.OnDecodeError); err != nil {
+		return err
+	}
+
+	// Nothing else is used while decoding
+	if cfg.Direction == DIRECTION_DECODE {
+		if cfg.Format == cloudevent.FORMAT_PROTOBUF {
+			return fmt.Errorf("direction '%s' only reads the '%s' format", DIRECTION_DECODE, cloudevent.FORMAT_JSON)
+		}
+		return nil
+	}
+
 	if len(cfg.Ce.AppendType) == 0 {
 		return errors.New("append_type field can not be empty")
 	} else {
@@ -36,5 +87,97 @@ func (cfg *Config) Validate() error {
 		return errors.New("source field can not be empty")
 	}
 
+	if err := cloudevent.ValidateSpecVersion(cfg.Ce.SpecVersion); err != nil {
+		return err
+	}
+
+	if err := cloudevent.ValidateDataSchema(cfg.Ce.DataSchema); err != nil {
+		return err
+	}
+
+	extensions := cloudevent.WithResourceExtensions(cfg.Ce.Extensions, cfg.ResourceAttributes)
+	if err := cloudevent.ValidateExtensions(extensions); err != nil {
+		return err
+	}
+
+	if len(cfg.Ce.PartitionKeyTemplate) > 0 {
+		if _, err := cloudevent.ParseTemplate("partition_key_template", cfg.Ce.PartitionKeyTemplate); err != nil {
+			return err
+		}
+
+		for _, ext := range extensions {
+			if ext.Name == EXTENSION_PARTITION_KEY {
+				return fmt.Errorf("extension '%s' can't be used along with partition_key_template", ext.Name)
+			}
+		}
+	}
+
+	if len(cfg.Ce.TypeTemplate) > 0 {
+		if _, err := cloudevent.ParseTemplate("type_template", cfg.Ce.TypeTemplate); err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.Ce.SubjectTemplate) > 0 {
+		if _, err := cloudevent.ParseTemplate("subject_template", cfg.Ce.SubjectTemplate); err != nil {
+			return err
+		}
+	}
+
+	if err := cloudevent.ValidateTimeConfig(cfg.Ce.TimeSource, cfg.Ce.OnInvalidTime); err != nil {
+		return err
+	}
+
+	if err := cfg.Filters.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Mapping.Validate(); err != nil {
+		return err
+	}
+
+	if err := cloudevent.ValidateResourceAttributes(cfg.ResourceAttributes, &cfg.Mapping); err != nil {
+		return err
+	}
+
+	if err := cfg.Redaction.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Dedup.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Traces.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Metrics.Validate(); err != nil {
+		return err
+	}
+
+	if err := cloudevent.ValidateMissingAttrPolicy(cfg.OnMissingAttributes, true); err != nil {
+		return err
+	}
+
+	switch cfg.Mode {
+	case "", MODE_STRUCTURED, MODE_BINARY, MODE_BATCH:
+	default:
+		return fmt.Errorf("mode must be one of '%s', '%s' or '%s', provided: %s", MODE_STRUCTURED, MODE_BINARY, MODE_BATCH, cfg.Mode)
+	}
+
+	if err := cfg.Batch.Validate(); err != nil {
+		return err
+	}
+
+	if err := cloudevent.ValidateFormat(cfg.Format, cfg.Ce.SpecVersion); err != nil {
+		return err
+	}
+
+	// Only the data is in the body in binary mode
+	if cfg.Format == cloudevent.FORMAT_PROTOBUF && cfg.Mode == MODE_BINARY {
+		return fmt.Errorf("format '%s' can only be used in '%s' or '%s' mode", cloudevent.FORMAT_PROTOBUF, MODE_STRUCTURED, MODE_BATCH)
+	}
+
 	return nil
 }
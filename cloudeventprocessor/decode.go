@@ -0,0 +1,269 @@
+package cloudeventtransform
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// Which way the logs are transformed
+	DIRECTION_ENCODE = "encode" // logs are converted to CloudEvents
+	DIRECTION_DECODE = "decode" // structured CloudEvents in the log bodies are converted back to attributes and body
+
+	// What to do with a log whose body isn't a valid CloudEvent while decoding
+	DECODE_ERROR_FAIL        = "fail"        // fail the whole batch
+	DECODE_ERROR_DROP        = "drop"        // drop the log record
+	DECODE_ERROR_PASSTHROUGH = "passthrough" // keep the log record as is with ATTR_DECODE_ERROR set
+
+	// Log attribute which has the reason a log couldn't be decoded with the passthrough policy
+	ATTR_DECODE_ERROR = "cloudevent.decode_error"
+)
+
+// cloudeventDecoder puts the attributes of structured CloudEvents in the log attributes with ATTR_CE_PREFIX
+type cloudeventDecoder struct {
+	onError   string
+	telemetry *processorTelemetry
+}
+
+// Checks the direction and the decode error policy
+func validateDirection(direction string, onDecodeError string) error {
+	switch direction {
+	case "", DIRECTION_ENCODE, DIRECTION_DECODE:
+	default:
+		return fmt.Errorf("direction should be one of '%s' or '%s', provided: %s", DIRECTION_ENCODE, DIRECTION_DECODE, direction)
+	}
+
+	switch onDecodeError {
+	case "", DECODE_ERROR_FAIL, DECODE_ERROR_DROP, DECODE_ERROR_PASSTHROUGH:
+	default:
+		return fmt.Errorf("on_decode_error should be one of '%s', '%s' or '%s', provided: %s",
+			DECODE_ERROR_FAIL, DECODE_ERROR_DROP, DECODE_ERROR_PASSTHROUGH, onDecodeError)
+	}
+
+	return nil
+}
+
+func newCloudeventDecoder(onError string, telemetry *processorTelemetry) *cloudeventDecoder {
+	if len(onError) == 0 {
+		onError = DECODE_ERROR_PASSTHROUGH
+	}
+	return &cloudeventDecoder{onError: onError, telemetry: telemetry}
+}
+
+// decodedRecord is a structured CloudEvent parsed out of the body of a log, which isn't written in the log yet
+type decodedRecord struct {
+	event     map[string]interface{}
+	spec      *cloudevent.SpecVersion
+	timestamp time.Time
+	data      []byte // bytes of base64 encoded data
+	hasData   bool
+	err       error // why the body isn't a valid CloudEvent, nothing else is set then
+}
+
+func (d *cloudeventDecoder) decodeLogs(ctx context.Context, ld plog.Logs) error {
+	decoded := make([]decodedRecord, 0, ld.LogRecordCount())
+
+	/*
+		Every record is parsed before any of them is modified, so that a batch which fails is left as it was
+		and a retry of it doesn't find records which are already decoded
+	*/
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		sls := ld.ResourceLogs().At(i).ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			records := sls.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				rec := parseLogRecord(records.At(k))
+				if rec.err != nil && d.onError == DECODE_ERROR_FAIL {
+					d.telemetry.recordDecodeError(ctx, d.onError)
+					return rec.err
+				}
+				decoded = append(decoded, rec)
+			}
+		}
+	}
+
+	n := 0
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				rec := &decoded[n]
+				n++
+
+				if rec.err == nil {
+					rec.writeTo(lr)
+					return false
+				}
+
+				d.telemetry.recordDecodeError(ctx, d.onError)
+				if d.onError == DECODE_ERROR_DROP {
+					return true
+				}
+				lr.Attributes().PutStr(ATTR_DECODE_ERROR, rec.err.Error())
+				return false
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+
+	return nil
+}
+
+/*
+Decodes the structured CloudEvent in the body of the log, the attributes (including extensions) are put in the
+log attributes with ATTR_CE_PREFIX, `time` becomes the Timestamp and `data` becomes the body. The log isn't
+modified if an error is returned
+*/
+func decodeLogRecord(lr plog.LogRecord) error {
+	rec := parseLogRecord(lr)
+	if rec.err != nil {
+		return rec.err
+	}
+
+	rec.writeTo(lr)
+	return nil
+}
+
+// Parses and validates the CloudEvent in the body of the log without modifying the log
+func parseLogRecord(lr plog.LogRecord) decodedRecord {
+	event, err := parseCloudEvent(lr.Body())
+	if err != nil {
+		return decodedRecord{err: err}
+	}
+
+	for _, name := range []string{"specversion", "id", "source", "type"} {
+		if str, ok := event[name].(string); !ok || len(str) == 0 {
+			return decodedRecord{err: fmt.Errorf("CloudEvent doesn't have the required attribute '%s'", name)}
+		}
+	}
+
+	spec, ok := cloudevent.SpecVersions[event["specversion"].(string)]
+	if !ok {
+		return decodedRecord{err: fmt.Errorf("CloudEvent spec version '%s' is not supported", event["specversion"])}
+	}
+
+	var timestamp time.Time
+	if val, ok := event["time"]; ok {
+		str, _ := val.(string)
+		if timestamp, err = time.Parse(time.RFC3339Nano, str); err != nil {
+			return decodedRecord{err: fmt.Errorf("CloudEvent time '%v' is not RFC 3339", val)}
+		}
+	}
+
+	data, hasData, err := decodeData(event, spec)
+	if err != nil {
+		return decodedRecord{err: err}
+	}
+
+	return decodedRecord{event: event, spec: spec, timestamp: timestamp, data: data, hasData: hasData}
+}
+
+// Writes the parsed CloudEvent in the log, it can't fail
+func (rec *decodedRecord) writeTo(lr plog.LogRecord) {
+	attrs := lr.Attributes()
+	for name, val := range rec.event {
+		if name == "data" || name == rec.spec.DataBase64Attr || name == rec.spec.DataEncodingAttr {
+			continue
+		}
+		putRawValue(attrs.PutEmpty(ATTR_CE_PREFIX+name), val)
+	}
+
+	if !rec.timestamp.IsZero() {
+		lr.SetTimestamp(pcommon.NewTimestampFromTime(rec.timestamp))
+	}
+
+	switch {
+	case !rec.hasData:
+		_ = lr.Body().FromRaw(nil)
+	case rec.data != nil:
+		lr.Body().SetEmptyBytes().FromRaw(rec.data)
+	default:
+		putRawValue(lr.Body(), rec.event["data"])
+	}
+}
+
+// The body can be JSON in a string or bytes, or a map if it was already parsed (ex: by a json_parser operator)
+func parseCloudEvent(body pcommon.Value) (map[string]interface{}, error) {
+	var raw []byte
+	switch body.Type() {
+	case pcommon.ValueTypeMap:
+		return body.Map().AsRaw(), nil
+	case pcommon.ValueTypeStr:
+		raw = []byte(body.Str())
+	case pcommon.ValueTypeBytes:
+		raw = body.Bytes().AsRaw()
+	default:
+		return nil, fmt.Errorf("body of type %s can't have a CloudEvent", body.Type())
+	}
+
+	// Numbers are kept as they are so that integers don't become floats
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var event map[string]interface{}
+	if err := decoder.Decode(&event); err != nil || event == nil {
+		return nil, errors.New("body is not a structured CloudEvent JSON object")
+	}
+	if decoder.More() {
+		return nil, errors.New("body has more than a CloudEvent JSON object")
+	}
+
+	return event, nil
+}
+
+// Returns the bytes of base64 encoded data, nil for any other data. hasData is false when the event doesn't have data
+func decodeData(event map[string]interface{}, spec *cloudevent.SpecVersion) (data []byte, hasData bool, err error) {
+	encoded, isBase64 := event[spec.DataBase64Attr].(string)
+	if spec.DataBase64Attr == "data" {
+		// 0.3 has base64 data in `data` along with datacontentencoding
+		isBase64 = isBase64 && event[spec.DataEncodingAttr] == cloudevent.DATA_ENCODING_BASE64
+	}
+
+	if !isBase64 {
+		_, hasData = event["data"]
+		return nil, hasData, nil
+	}
+
+	if data, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+		return nil, false, fmt.Errorf("CloudEvent %s is not valid base64", spec.DataBase64Attr)
+	}
+	return data, true, nil
+}
+
+// Sets the value parsed from JSON (or from pcommon.Map.AsRaw) in val
+func putRawValue(val pcommon.Value, raw interface{}) {
+	switch v := raw.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			val.SetInt(i)
+		} else if f, err := v.Float64(); err == nil {
+			val.SetDouble(f)
+		} else {
+			val.SetStr(v.String())
+		}
+	case map[string]interface{}:
+		m := val.SetEmptyMap()
+		m.EnsureCapacity(len(v))
+		for k, item := range v {
+			putRawValue(m.PutEmpty(k), item)
+		}
+	case []interface{}:
+		s := val.SetEmptySlice()
+		s.EnsureCapacity(len(v))
+		for _, item := range v {
+			putRawValue(s.AppendEmpty(), item)
+		}
+	default:
+		// Strings, bools, nil and the values of pcommon.Map.AsRaw
+		_ = val.FromRaw(v)
+	}
+}
@@ -0,0 +1,110 @@
+package cloudeventtransform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestValidateDirection(t *testing.T) {
+	assert.NoError(t, validateDirection("", ""))
+	assert.NoError(t, validateDirection(DIRECTION_DECODE, DECODE_ERROR_DROP))
+	assert.Error(t, validateDirection("reverse", ""))
+	assert.Error(t, validateDirection(DIRECTION_DECODE, "skip"))
+}
+
+func TestDecodeLogRecord(t *testing.T) {
+	lr := plog.NewLogRecord()
+	lr.Body().SetStr(`{"datacontenttype":"application/json; charset=utf-8","id":"abcdefgh","source":"cluster/test",` +
+		`"specversion":"1.0","type":"com.company.event.v1.BackOff","time":"2023-03-01T10:00:00Z","count":3,` +
+		`"data":{"message":"Back-off","count":3,"ratio":0.5,"tags":["a",true,null]}}`)
+	require.NoError(t, decodeLogRecord(lr))
+
+	attrs := lr.Attributes().AsRaw()
+	assert.Equal(t, "abcdefgh", attrs[ATTR_CE_ID])
+	assert.Equal(t, "cluster/test", attrs[ATTR_CE_SOURCE])
+	assert.Equal(t, "1.0", attrs[ATTR_CE_SPECVERSION])
+	assert.Equal(t, "com.company.event.v1.BackOff", attrs[ATTR_CE_TYPE])
+	assert.Equal(t, "2023-03-01T10:00:00Z", attrs[ATTR_CE_TIME])
+	assert.Equal(t, int64(3), attrs[ATTR_CE_PREFIX+"count"])
+	assert.NotContains(t, attrs, ATTR_CE_PREFIX+"data")
+	assert.Equal(t, time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC), lr.Timestamp().AsTime())
+
+	require.Equal(t, pcommon.ValueTypeMap, lr.Body().Type())
+	assert.Equal(t, map[string]interface{}{
+		"message": "Back-off",
+		"count":   int64(3),
+		"ratio":   0.5,
+		"tags":    []interface{}{"a", true, nil},
+	}, lr.Body().Map().AsRaw())
+}
+
+func TestDecodeLogRecordData(t *testing.T) {
+	const required = `"id":"1","source":"s","type":"t",`
+
+	tests := []struct {
+		name     string
+		body     string
+		expected interface{}
+	}{
+		{"base64", `{` + required + `"specversion":"1.0","data_base64":"AAEC"}`, []byte{0, 1, 2}},
+		{"base64 0.3", `{` + required + `"specversion":"0.3","datacontentencoding":"base64","data":"AAEC"}`, []byte{0, 1, 2}},
+		{"string 0.3", `{` + required + `"specversion":"0.3","data":"AAEC"}`, "AAEC"},
+		{"string", `{` + required + `"specversion":"1.0","data":"message"}`, "message"},
+		{"array", `{` + required + `"specversion":"1.0","data":[1,"a"]}`, []interface{}{int64(1), "a"}},
+		{"none", `{` + required + `"specversion":"1.0"}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := plog.NewLogRecord()
+			lr.Body().SetEmptyBytes().FromRaw([]byte(tt.body))
+			require.NoError(t, decodeLogRecord(lr))
+			assert.Equal(t, tt.expected, lr.Body().AsRaw())
+
+			attrs := lr.Attributes().AsRaw()
+			assert.NotContains(t, attrs, ATTR_CE_PREFIX+"data_base64")
+			assert.NotContains(t, attrs, ATTR_CE_PREFIX+"datacontentencoding")
+		})
+	}
+}
+
+func TestDecodeLogRecordMapBody(t *testing.T) {
+	lr := plog.NewLogRecord()
+	require.NoError(t, lr.Body().SetEmptyMap().FromRaw(map[string]interface{}{
+		"specversion": "1.0", "id": "1", "source": "s", "type": "t", "data": map[string]interface{}{"count": int64(3)},
+	}))
+	require.NoError(t, decodeLogRecord(lr))
+	assert.Equal(t, map[string]interface{}{"count": int64(3)}, lr.Body().AsRaw())
+	assert.Equal(t, "1", lr.Attributes().AsRaw()[ATTR_CE_ID])
+}
+
+func TestDecodeLogRecordInvalid(t *testing.T) {
+	for _, body := range []string{
+		`Back-off restarting container`,
+		`[{"specversion":"1.0","id":"1","source":"s","type":"t"}]`,
+		`{"specversion":"1.0","id":"1","source":"s","type":"t"} {}`,
+		`{"specversion":"1.0","id":"1","source":"s"}`,
+		`{"specversion":"1.0","id":1,"source":"s","type":"t"}`,
+		`{"specversion":"2.0","id":"1","source":"s","type":"t"}`,
+		`{"specversion":"1.0","id":"1","source":"s","type":"t","time":"yesterday"}`,
+		`{"specversion":"1.0","id":"1","source":"s","type":"t","data_base64":"not base64"}`,
+		`null`,
+	} {
+		lr := plog.NewLogRecord()
+		lr.Body().SetStr(body)
+		assert.Error(t, decodeLogRecord(lr), body)
+
+		// The log is left as it was
+		assert.Equal(t, body, lr.Body().Str())
+		assert.Equal(t, 0, lr.Attributes().Len())
+	}
+
+	lr := plog.NewLogRecord()
+	lr.Body().SetInt(1)
+	assert.Error(t, decodeLogRecord(lr))
+}
@@ -0,0 +1,180 @@
+package cloudeventtransform
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// What happens to a log which was already seen
+	DEDUP_ACTION_DROP = "drop" // remove the log
+	DEDUP_ACTION_TAG  = "tag"  // convert it with ATTR_DUPLICATE set
+
+	// Log attribute set on the duplicates with the tag action
+	ATTR_DUPLICATE = "cloudevent.duplicate"
+
+	DEFAULT_DEDUP_MAX_ENTRIES = 10000
+	DEFAULT_DEDUP_TTL         = time.Hour
+)
+
+/*
+DedupConfig enables deduplication of the logs, k8seventsreceiver sends the same event again when its count
+is bumped (with the new count) and after restarts (with the same count)
+*/
+type DedupConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Fields     []string      `mapstructure:"fields"`      // key of a log, k8s.event.uid and k8s.event.count when empty
+	MaxEntries int           `mapstructure:"max_entries"` // keys which are remembered, least recently seen are evicted first
+	TTL        time.Duration `mapstructure:"ttl"`         // a key is forgotten after this long since it was first seen
+	Action     string        `mapstructure:"action"`      // drop (default) or tag
+}
+
+// deduplicator is a bounded LRU of the keys of the logs which were seen in the last TTL
+type deduplicator struct {
+	fields     []cloudevent.FieldRef
+	maxEntries int
+	ttl        time.Duration
+	action     string
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently seen
+}
+
+type dedupEntry struct {
+	key     string
+	expires time.Time
+}
+
+// Validate checks the deduplication configuration
+func (cfg *DedupConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if cfg.MaxEntries < 0 {
+		return fmt.Errorf("dedup.max_entries can not be negative, provided: %d", cfg.MaxEntries)
+	}
+
+	if cfg.TTL < 0 {
+		return fmt.Errorf("dedup.ttl can not be negative, provided: %s", cfg.TTL)
+	}
+
+	for _, f := range cfg.Fields {
+		if len(f) == 0 {
+			return errors.New("dedup.fields can not have empty fields")
+		}
+	}
+
+	switch cfg.Action {
+	case "", DEDUP_ACTION_DROP, DEDUP_ACTION_TAG:
+	default:
+		return fmt.Errorf("dedup.action should be one of '%s' or '%s', provided: %s", DEDUP_ACTION_DROP, DEDUP_ACTION_TAG, cfg.Action)
+	}
+
+	return nil
+}
+
+// Returns nil if deduplication isn't enabled
+func newDeduplicator(cfg *DedupConfig) *deduplicator {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	d := &deduplicator{
+		maxEntries: cfg.MaxEntries,
+		ttl:        cfg.TTL,
+		action:     cfg.Action,
+		now:        time.Now,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+
+	fields := cfg.Fields
+	if len(fields) == 0 {
+		fields = []string{cloudevent.ATTR_EVENT_UID, cloudevent.ATTR_EVENT_COUNT}
+	}
+	for _, f := range fields {
+		d.fields = append(d.fields, cloudevent.NewFieldRef(f))
+	}
+
+	if d.maxEntries == 0 {
+		d.maxEntries = DEFAULT_DEDUP_MAX_ENTRIES
+	}
+	if d.ttl == 0 {
+		d.ttl = DEFAULT_DEDUP_TTL
+	}
+	if len(d.action) == 0 {
+		d.action = DEDUP_ACTION_DROP
+	}
+
+	return d
+}
+
+/*
+Returns the key of the log, false if the log doesn't have all the fields and can't be deduplicated
+otherwise every log without those fields would be a duplicate of the first one
+*/
+func (d *deduplicator) key(res pcommon.Resource, lr plog.LogRecord) (string, bool) {
+	values := make([]pcommon.Value, 0, len(d.fields))
+	for i := range d.fields {
+		val, ok := d.fields[i].Get(res, lr)
+		if !ok {
+			return "", false
+		}
+		values = append(values, val)
+	}
+
+	return cloudevent.NewHashID(values), true
+}
+
+// Returns true if the key was seen in the last TTL, the key is remembered otherwise
+func (d *deduplicator) seen(key string) bool {
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.entries[key]; ok {
+		d.lru.MoveToFront(el)
+
+		entry := el.Value.(*dedupEntry)
+		if now.Before(entry.expires) {
+			return true
+		}
+
+		entry.expires = now.Add(d.ttl)
+		return false
+	}
+
+	d.entries[key] = d.lru.PushFront(&dedupEntry{key: key, expires: now.Add(d.ttl)})
+
+	for d.lru.Len() > d.maxEntries {
+		oldest := d.lru.Back()
+		d.lru.Remove(oldest)
+		delete(d.entries, oldest.Value.(*dedupEntry).key)
+	}
+
+	return false
+}
+
+// Forgets the keys, used when the batch which had them failed so that its retry isn't taken as duplicates
+func (d *deduplicator) forget(keys []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := d.entries[key]; ok {
+			d.lru.Remove(el)
+			delete(d.entries, key)
+		}
+	}
+}
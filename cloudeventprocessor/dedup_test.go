@@ -0,0 +1,87 @@
+package cloudeventtransform
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestDedupConfigValidate(t *testing.T) {
+	assert.NoError(t, (&DedupConfig{}).Validate())
+	assert.NoError(t, (&DedupConfig{Enabled: true, Action: DEDUP_ACTION_TAG}).Validate())
+	assert.NoError(t, (&DedupConfig{MaxEntries: -1}).Validate()) // not checked when disabled
+
+	for _, cfg := range []DedupConfig{
+		{Enabled: true, MaxEntries: -1},
+		{Enabled: true, TTL: -time.Second},
+		{Enabled: true, Fields: []string{""}},
+		{Enabled: true, Action: "skip"},
+	} {
+		assert.Error(t, cfg.Validate(), cfg)
+	}
+}
+
+func TestDeduplicatorKey(t *testing.T) {
+	d := newDeduplicator(&DedupConfig{Enabled: true})
+	res := pcommon.NewResource()
+	lr := plog.NewLogRecord()
+
+	// Logs without the key fields aren't duplicates of each other
+	_, ok := d.key(res, lr)
+	assert.False(t, ok)
+	lr.Attributes().PutStr(cloudevent.ATTR_EVENT_UID, "abcdefgh")
+	_, ok = d.key(res, lr)
+	assert.False(t, ok)
+
+	lr.Attributes().PutInt(cloudevent.ATTR_EVENT_COUNT, 1)
+	first, ok := d.key(res, lr)
+	assert.True(t, ok)
+
+	// Bumped count is a new event
+	lr.Attributes().PutInt(cloudevent.ATTR_EVENT_COUNT, 2)
+	second, _ := d.key(res, lr)
+	assert.NotEqual(t, first, second)
+
+	assert.Nil(t, newDeduplicator(&DedupConfig{}))
+}
+
+func TestDeduplicatorSeen(t *testing.T) {
+	now := time.Unix(1000, 0)
+	d := newDeduplicator(&DedupConfig{Enabled: true, MaxEntries: 2, TTL: time.Minute})
+	d.now = func() time.Time { return now }
+
+	assert.False(t, d.seen("a"))
+	assert.True(t, d.seen("a"))
+	assert.False(t, d.seen("b"))
+
+	// "a" is the most recently seen so "b" is evicted
+	assert.True(t, d.seen("a"))
+	assert.False(t, d.seen("c"))
+	assert.False(t, d.seen("b"))
+	assert.Equal(t, 2, d.lru.Len())
+	assert.Len(t, d.entries, 2)
+
+	// Seen again after the TTL
+	now = now.Add(time.Minute)
+	assert.False(t, d.seen("b"))
+	assert.True(t, d.seen("b"))
+
+	d.forget([]string{"b", "unknown"})
+	assert.False(t, d.seen("b"))
+}
+
+func TestDeduplicatorBounded(t *testing.T) {
+	d := newDeduplicator(&DedupConfig{Enabled: true, MaxEntries: 100})
+	for i := 0; i < 1000; i++ {
+		d.seen(fmt.Sprint(i))
+	}
+	assert.Equal(t, 100, d.lru.Len())
+	assert.Len(t, d.entries, 100)
+	assert.True(t, d.seen("999"))
+	assert.False(t, d.seen("0"))
+}
@@ -0,0 +1,138 @@
+package cloudeventtransform
+
+import (
+	"encoding/base64"
+	"sync"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	// Scratch buffers which grew bigger than this (ex: a huge message) aren't kept in the pool
+	MAX_POOLED_BUFFER_SIZE = 64 << 10
+	INITIAL_BUFFER_SIZE    = 1 << 10
+)
+
+// Keys of the optional attributes with the comma before them, ex: `,"subject":`
+var (
+	fragmentSubject      = jsonKeyFragment(true, "subject")
+	fragmentTime         = jsonKeyFragment(true, "time")
+	fragmentTraceparent  = jsonKeyFragment(true, cloudevent.EXTENSION_TRACEPARENT)
+	fragmentPartitionKey = jsonKeyFragment(true, EXTENSION_PARTITION_KEY)
+	fragmentData         = jsonKeyFragment(true, "data")
+)
+
+// Scratch buffers the events are encoded in before they're copied to the body which needs its own memory
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, INITIAL_BUFFER_SIZE)
+		return &buf
+	},
+}
+
+/*
+envelopeEncoder has the parts of the envelope which are the same for every event of a processor, already
+escaped, so that encoding an event only escapes what comes from the log
+*/
+type envelopeEncoder struct {
+	headJson      []byte   // `{"datacontenttype":"application/json; charset=utf-8","id":`
+	headBytes     []byte   // same as headJson with application/octet-stream (and datacontentencoding for 0.3)
+	sourceType    []byte   // `,"source":"...","specversion":"1.0","type":`
+	dataSchema    []byte   // `,"dataschema":"..."` (schemaurl for 0.3), empty if there isn't one
+	extensionKeys [][]byte // `,"name":` of every extension in the same order
+	dataBase64    []byte   // `,"data_base64":` (data for 0.3)
+	dataKeys      [][]byte // `"key":` of every mapping.data key in the same order
+	attributesKey []byte   // `"key":` of mapping.include_attributes, empty if it's not set
+}
+
+func newEnvelopeEncoder(source string, dataSchema string, spec *cloudevent.SpecVersion, extensions []cloudevent.Extension, m *cloudevent.Mapping) *envelopeEncoder {
+	enc := &envelopeEncoder{}
+
+	enc.headJson = append(enc.headJson, cloudevent.OPEN_BRACE_BYTE)
+	enc.headJson = cloudevent.AppendJsonObjStr("datacontenttype", CONTENT_TYPE_JSON, enc.headJson)
+	enc.headJson = append(enc.headJson, jsonKeyFragment(true, "id")...)
+
+	enc.headBytes = append(enc.headBytes, cloudevent.OPEN_BRACE_BYTE)
+	enc.headBytes = cloudevent.AppendJsonObjStr("datacontenttype", CONTENT_TYPE_OCTET_STREAM, enc.headBytes)
+	if len(spec.DataEncodingAttr) > 0 {
+		enc.headBytes = append(enc.headBytes, cloudevent.COMMA_BYTE)
+		enc.headBytes = cloudevent.AppendJsonObjStr(spec.DataEncodingAttr, cloudevent.DATA_ENCODING_BASE64, enc.headBytes)
+	}
+	enc.headBytes = append(enc.headBytes, jsonKeyFragment(true, "id")...)
+
+	enc.sourceType = append(enc.sourceType, cloudevent.COMMA_BYTE)
+	enc.sourceType = cloudevent.AppendJsonObjStr("source", source, enc.sourceType)
+	enc.sourceType = append(enc.sourceType, cloudevent.COMMA_BYTE)
+	enc.sourceType = cloudevent.AppendJsonObjStr("specversion", spec.Version, enc.sourceType)
+	enc.sourceType = append(enc.sourceType, jsonKeyFragment(true, "type")...)
+
+	if len(dataSchema) > 0 {
+		enc.dataSchema = cloudevent.AppendJsonObjStr(spec.DataSchemaAttr, dataSchema, []byte{cloudevent.COMMA_BYTE})
+	}
+
+	for i := range extensions {
+		enc.extensionKeys = append(enc.extensionKeys, jsonKeyFragment(true, extensions[i].Name))
+	}
+
+	enc.dataBase64 = jsonKeyFragment(true, spec.DataBase64Attr)
+
+	for i := range m.Data {
+		enc.dataKeys = append(enc.dataKeys, jsonKeyFragment(false, m.Data[i].Key))
+	}
+	if len(m.AttributesKey) > 0 {
+		enc.attributesKey = jsonKeyFragment(false, m.AttributesKey)
+	}
+
+	return enc
+}
+
+// Escaped `"key":`, with a comma before it if comma is true
+func jsonKeyFragment(comma bool, key string) []byte {
+	var ret []byte
+	if comma {
+		ret = append(ret, cloudevent.COMMA_BYTE)
+	}
+	return cloudevent.AppendJsonObjElse(key, nil, ret)
+}
+
+// Appends the bytes as a base64 JSON string without an intermediate string
+func appendJsonBase64(data []byte, retSlice []byte) []byte {
+	retSlice = append(retSlice, cloudevent.QUOTE_BYTE)
+
+	n := len(retSlice)
+	size := base64.StdEncoding.EncodedLen(len(data))
+	if cap(retSlice)-n < size {
+		grown := make([]byte, n, n+size+1)
+		copy(grown, retSlice)
+		retSlice = grown
+	}
+	retSlice = retSlice[:n+size]
+	base64.StdEncoding.Encode(retSlice[n:], data)
+
+	return append(retSlice, cloudevent.QUOTE_BYTE)
+}
+
+func getEncodeBuffer() *[]byte {
+	return encodeBufferPool.Get().(*[]byte)
+}
+
+// Puts the buffer back in the pool, used is what it was grown to while encoding
+func putEncodeBuffer(buf *[]byte, used []byte) {
+	if cap(used) > MAX_POOLED_BUFFER_SIZE {
+		return
+	}
+	*buf = used[:0]
+	encodeBufferPool.Put(buf)
+}
+
+/*
+Replaces the value with the encoded event, the body gets exactly as much memory as the event needs. pdata copies
+the bytes of a body (FromRaw too) and doesn't give its slice to encode in, so the event is copied once from the
+scratch buffer. It can't be encoded in the body in place anyway as the body may be one of the values encoded
+*/
+func setBytesBody(body pcommon.Value, event []byte) {
+	bs := body.SetEmptyBytes()
+	bs.EnsureCapacity(len(event))
+	bs.Append(event...)
+}
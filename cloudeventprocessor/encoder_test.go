@@ -0,0 +1,291 @@
+package cloudeventtransform
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudeventtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestJsonKeyFragment(t *testing.T) {
+	assert.Equal(t, `"id":`, string(jsonKeyFragment(false, "id")))
+	assert.Equal(t, `,"say \"hi\"":`, string(jsonKeyFragment(true, `say "hi"`)))
+}
+
+func TestAppendJsonBase64(t *testing.T) {
+	for _, data := range [][]byte{nil, {0}, {0, 1, 2}, []byte("a longer value than the buffer has room for")} {
+		for _, buf := range [][]byte{nil, make([]byte, 0, 4), make([]byte, 0, 128)} {
+			out := appendJsonBase64(data, append(buf, '['))
+			assert.Equal(t, `["`+base64.StdEncoding.EncodeToString(data)+`"`, string(out))
+		}
+	}
+}
+
+func TestEncodeBufferPool(t *testing.T) {
+	buf := getEncodeBuffer()
+	assert.Equal(t, 0, len(*buf))
+
+	// Oversized buffers are left to the GC
+	putEncodeBuffer(buf, make([]byte, 10, MAX_POOLED_BUFFER_SIZE+1))
+	putEncodeBuffer(buf, append(*buf, "event"...))
+	assert.Equal(t, 0, len(*buf))
+}
+
+// Processor and the extracted data of a k8s event, ready to be encoded
+func benchmarkEvent(t testing.TB, mode string, format string) (*cloudeventTransformProcessor, *cloudeventdata) {
+	cfg := testConfig()
+	cfg.Mode = mode
+	cfg.Format = format
+	cfg.Ce.Extensions = []cloudevent.ExtensionConfig{{Name: "cluster", Value: "east-1"}}
+	p, err := newProcessor(componenttest.NewNopTelemetrySettings(), cfg)
+	require.NoError(t, err)
+
+	lr := plog.NewLogRecord()
+	fillK8sEvent(lr, "BackOff")
+	lr.Attributes().PutStr(cloudevent.ATTR_OBJECT_KIND, "Pod")
+	lr.Attributes().PutStr(cloudevent.ATTR_OBJECT_NAME, "pod-1")
+	lr.Attributes().PutStr(ATTR_OBJECT_UID, "6a0c1f2e-5b1d-4a3e-9f7c-2d8e4b6a1c3f")
+
+	ev := &cloudeventdata{}
+	res := pcommon.NewResource()
+	require.Empty(t, p.mapping.Extract(res, lr, &ev.Event))
+	ev.Subject, err = p.subjectBuilder.Build(p.mapping, res, lr, &ev.Event)
+	require.NoError(t, err)
+	ev.typ, err = p.typeBuilder.Build(p.mapping, res, lr, &ev.Event)
+	require.NoError(t, err)
+	ev.time, err = p.timeResolver.Resolve(res, lr)
+	require.NoError(t, err)
+	ev.extensions = cloudevent.AppendExtensionValues(nil, p.extensions, res, lr)
+	ev.partitionKey, err = p.partitionKeyBuilder.build(p.mapping, res, lr, ev)
+	require.NoError(t, err)
+
+	return p, ev
+}
+
+func TestEnvelopeEncoderAllocations(t *testing.T) {
+	tests := []struct {
+		mode   string
+		format string
+	}{
+		{MODE_STRUCTURED, cloudevent.FORMAT_JSON},
+		{MODE_BINARY, cloudevent.FORMAT_JSON},
+		{MODE_BATCH, cloudevent.FORMAT_JSON},
+		{MODE_STRUCTURED, cloudevent.FORMAT_PROTOBUF},
+		{MODE_BATCH, cloudevent.FORMAT_PROTOBUF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.format, func(t *testing.T) {
+			p, ev := benchmarkEvent(t, tt.mode, tt.format)
+
+			buf := make([]byte, 0, INITIAL_BUFFER_SIZE)
+			allocs := testing.AllocsPerRun(100, func() {
+				buf = p.encodeEvent(buf[:0], ev)
+			})
+			assert.Zero(t, allocs)
+
+			if tt.format == cloudevent.FORMAT_PROTOBUF {
+				cloudeventtest.UnmarshalProtoEvent(t, buf)
+			} else {
+				assert.True(t, json.Valid(buf))
+			}
+
+			// Only the body allocates, its bytes value and the copy of the event
+			body := pcommon.NewValueEmpty()
+			allocs = testing.AllocsPerRun(100, func() {
+				scratch := getEncodeBuffer()
+				event := p.encodeEvent(*scratch, ev)
+				setBytesBody(body, event)
+				putEncodeBuffer(scratch, event)
+			})
+			assert.Equal(t, float64(2), allocs)
+			assert.Equal(t, buf, body.Bytes().AsRaw())
+		})
+	}
+}
+
+// Same as the envelope written by encoding/json, so that the benchmarks compare the same output
+type jsonEnvelope struct {
+	DataContentType string                 `json:"datacontenttype"`
+	ID              string                 `json:"id"`
+	Source          string                 `json:"source"`
+	SpecVersion     string                 `json:"specversion"`
+	Type            string                 `json:"type"`
+	Subject         string                 `json:"subject,omitempty"`
+	Time            string                 `json:"time,omitempty"`
+	Cluster         string                 `json:"cluster,omitempty"`
+	PartitionKey    string                 `json:"partitionkey,omitempty"`
+	Data            map[string]interface{} `json:"data"`
+}
+
+/*
+Envelope as it was encoded before the encoder had the escaped constant parts and the pooled buffers, every event
+got its own 512 bytes and the keys and values were converted to []byte. Kept to compare the allocations, only the
+JSON data of the benchmark event is handled
+*/
+func constructCloudEventJsonBodyUnpooled(ce *cloudeventTransformProcessor, msgData *cloudeventdata) []byte {
+	retSlice := make([]byte, 0, 512)
+
+	retSlice = append(retSlice, cloudevent.OPEN_BRACE_BYTE)
+	retSlice = appendUnpooledObjStr([]byte("datacontenttype"), []byte(CONTENT_TYPE_JSON), retSlice)
+	retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	retSlice = appendUnpooledObjStr([]byte("id"), []byte(msgData.ID), retSlice)
+	retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	retSlice = appendUnpooledObjStr([]byte("source"), []byte(ce.source), retSlice)
+	retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	retSlice = appendUnpooledObjStr([]byte("specversion"), []byte(ce.spec.Version), retSlice)
+	retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	retSlice = appendUnpooledObjStr([]byte("type"), []byte(msgData.typ), retSlice)
+	retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	if len(msgData.Subject) > 0 {
+		retSlice = appendUnpooledObjStr([]byte("subject"), []byte(msgData.Subject), retSlice)
+		retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	}
+	if len(msgData.time) > 0 {
+		retSlice = appendUnpooledObjStr([]byte("time"), []byte(msgData.time), retSlice)
+		retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	}
+	for i, val := range msgData.extensions {
+		if len(val) > 0 {
+			retSlice = appendUnpooledObjStr([]byte(ce.extensions[i].Name), []byte(val), retSlice)
+			retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+		}
+	}
+	if len(msgData.partitionKey) > 0 {
+		retSlice = appendUnpooledObjStr([]byte(EXTENSION_PARTITION_KEY), []byte(msgData.partitionKey), retSlice)
+		retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+	}
+
+	retSlice = appendUnpooledKey([]byte("data"), retSlice)
+	retSlice = append(retSlice, cloudevent.OPEN_BRACE_BYTE)
+	for i, val := range msgData.Data {
+		if i > 0 {
+			retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+		}
+		retSlice = cloudevent.AppendJsonValue(val, appendUnpooledKey([]byte(ce.mapping.Data[i].Key), retSlice))
+	}
+	retSlice = append(retSlice, cloudevent.CLOSE_BRACE_BYTE)
+
+	return append(retSlice, cloudevent.CLOSE_BRACE_BYTE)
+}
+
+// Appends `"key":`, the keys of the benchmark event don't need escaping
+func appendUnpooledKey(key []byte, retSlice []byte) []byte {
+	retSlice = append(retSlice, cloudevent.QUOTE_BYTE)
+	retSlice = append(retSlice, key...)
+	return append(retSlice, cloudevent.QUOTE_BYTE, cloudevent.COLON_BYTE)
+}
+
+func appendUnpooledObjStr(key []byte, val []byte, retSlice []byte) []byte {
+	return cloudevent.AppendJsonStr(string(val), appendUnpooledKey(key, retSlice))
+}
+
+func TestUnpooledEnvelopeIsTheSame(t *testing.T) {
+	p, ev := benchmarkEvent(t, MODE_STRUCTURED, cloudevent.FORMAT_JSON)
+	assert.Equal(t, string(p.constructCloudEventJsonBody(nil, ev)), string(constructCloudEventJsonBodyUnpooled(p, ev)))
+}
+
+func TestEnvelopeIsTheSameAsEncodingJson(t *testing.T) {
+	p, ev := benchmarkEvent(t, MODE_STRUCTURED, cloudevent.FORMAT_JSON)
+
+	expected := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(p.constructCloudEventJsonBody(nil, ev), &expected))
+	actual := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(encodeWithEncodingJson(p, ev), &actual))
+	assert.Equal(t, expected, actual)
+}
+
+func encodeWithEncodingJson(ce *cloudeventTransformProcessor, msgData *cloudeventdata) []byte {
+	data := make(map[string]interface{}, len(msgData.Data))
+	for i, val := range msgData.Data {
+		data[ce.mapping.Data[i].Key] = val.AsRaw()
+	}
+
+	ret, _ := json.Marshal(&jsonEnvelope{
+		DataContentType: CONTENT_TYPE_JSON,
+		ID:              msgData.ID,
+		Source:          ce.source,
+		SpecVersion:     ce.spec.Version,
+		Type:            msgData.typ,
+		Subject:         msgData.Subject,
+		Time:            msgData.time,
+		Cluster:         msgData.extensions[0],
+		PartitionKey:    msgData.partitionKey,
+		Data:            data,
+	})
+	return ret
+}
+
+// Encoding of one event along with writing it in the log body, allocs/op is per record
+func BenchmarkEnvelope(b *testing.B) {
+	body := pcommon.NewValueEmpty() // not the body of the log, the message is read from there
+
+	for _, format := range []string{cloudevent.FORMAT_JSON, cloudevent.FORMAT_PROTOBUF} {
+		p, ev := benchmarkEvent(b, MODE_STRUCTURED, format)
+
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf := getEncodeBuffer()
+				event := p.encodeEvent(*buf, ev)
+				setBytesBody(body, event)
+				putEncodeBuffer(buf, event)
+			}
+		})
+	}
+
+	p, ev := benchmarkEvent(b, MODE_STRUCTURED, cloudevent.FORMAT_JSON)
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			setBytesBody(body, constructCloudEventJsonBodyUnpooled(p, ev))
+		}
+	})
+
+	b.Run("encoding_json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			setBytesBody(body, encodeWithEncodingJson(p, ev))
+		}
+	})
+}
+
+// Whole conversion of a batch of k8s events, allocs/op is per batch of BENCHMARK_RECORDS records
+func BenchmarkProcessLogs(b *testing.B) {
+	const BENCHMARK_RECORDS = 100
+
+	for _, mode := range []string{MODE_STRUCTURED, MODE_BINARY, MODE_BATCH} {
+		b.Run(mode, func(b *testing.B) {
+			cfg := testConfig()
+			cfg.Mode = mode
+			p, err := newProcessor(componenttest.NewNopTelemetrySettings(), cfg)
+			require.NoError(b, err)
+
+			logs := plog.NewLogs()
+			records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+			for i := 0; i < BENCHMARK_RECORDS; i++ {
+				fillK8sEvent(records.AppendEmpty(), "BackOff")
+			}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				ld := plog.NewLogs()
+				logs.CopyTo(ld)
+				b.StartTimer()
+
+				if _, err := p.processLogs(context.Background(), ld); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
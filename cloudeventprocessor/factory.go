@@ -3,7 +3,9 @@ package cloudeventtransform
 import (
 	"context"
 	"errors"
+	"fmt"
 
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/processor"
@@ -24,14 +26,55 @@ func NewFactory() processor.Factory {
 		typeStr,
 		CreateDefaultConfig,
 		processor.WithLogs(createLogsProcessor, stability),
+		processor.WithTraces(createTracesProcessor, stability),
+		processor.WithMetrics(createMetricsProcessor, stability),
 	)
 }
 
 func CreateDefaultConfig() component.Config {
 	return &Config{
 		Ce: CloudEventSpec{
-			SpecVersion: "1.0",
+			SpecVersion:     "1.0",
+			TypeTemplate:    cloudevent.DEFAULT_TYPE_TEMPLATE,
+			SubjectTemplate: cloudevent.DEFAULT_SUBJECT_TEMPLATE,
+			TimeSource:      cloudevent.TIME_SOURCE_MAPPING,
+			OnInvalidTime:   cloudevent.INVALID_TIME_OMIT,
+
+			PartitionKeyTemplate: DEFAULT_PARTITION_KEY_TEMPLATE,
+		},
+		Mode: MODE_STRUCTURED,
+		Mapping: cloudevent.MappingConfig{
+			ID:         cloudevent.ATTR_EVENT_UID,
+			Time:       cloudevent.ATTR_EVENT_START_TIME,
+			TypeSuffix: cloudevent.ATTR_EVENT_REASON,
+
+			ParseJSONBody: true,
+		},
+		Traces: TracesConfig{
+			Mapping: cloudevent.MappingConfig{
+				ID:                cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_SPAN_ID,
+				Time:              cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_END_TIME,
+				TypeSuffix:        cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_EVENT,
+				IncludeAttributes: "attributes",
+			},
+		},
+		Metrics: MetricsConfig{
+			Mapping: cloudevent.MappingConfig{
+				ID:                cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_EVENT_ID,
+				Time:              cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_TIME,
+				TypeSuffix:        cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_EVENT,
+				IncludeAttributes: "attributes",
+			},
+		},
+		Dedup: DedupConfig{
+			MaxEntries: DEFAULT_DEDUP_MAX_ENTRIES,
+			TTL:        DEFAULT_DEDUP_TTL,
+			Action:     DEDUP_ACTION_DROP,
 		},
+		Direction:             DIRECTION_ENCODE,
+		OnDecodeError:         DECODE_ERROR_PASSTHROUGH,
+		OnMissingAttributes:   cloudevent.MISSING_ATTR_FAIL,
+		PartitionKeyAttribute: ATTR_PARTITION_KEY,
 	}
 }
 
@@ -60,3 +103,66 @@ func createLogsProcessor(
 		processorhelper.WithCapabilities(ceProcessor.Capabilities()),
 	)
 }
+
+func createTracesProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Traces) (processor.Traces, error) {
+
+	pCfg, ok := cfg.(*Config)
+	if !ok {
+		return nil, errors.New("could not initialize cloud-event transform processor")
+	}
+
+	if pCfg.Direction == DIRECTION_DECODE {
+		return nil, fmt.Errorf("direction '%s' is only supported in logs pipelines", DIRECTION_DECODE)
+	}
+
+	converter, err := newSpanConverter(set.TelemetrySettings, pCfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create cloud-event processor for traces: %w", err)
+	}
+
+	return processorhelper.NewTracesProcessor(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		converter.processTraces,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		processorhelper.WithStart(converter.start),
+	)
+}
+
+func createMetricsProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics) (processor.Metrics, error) {
+
+	pCfg, ok := cfg.(*Config)
+	if !ok {
+		return nil, errors.New("could not initialize cloud-event transform processor")
+	}
+
+	if pCfg.Direction == DIRECTION_DECODE {
+		return nil, fmt.Errorf("direction '%s' is only supported in logs pipelines", DIRECTION_DECODE)
+	}
+
+	converter, err := newMetricConverter(set.TelemetrySettings, pCfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create cloud-event processor for metrics: %w", err)
+	}
+
+	return processorhelper.NewMetricsProcessor(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		converter.processMetrics,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		processorhelper.WithStart(converter.start),
+		processorhelper.WithShutdown(converter.shutdown),
+	)
+}
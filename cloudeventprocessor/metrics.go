@@ -0,0 +1,520 @@
+package cloudeventtransform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	// How the value of a data point is compared with the threshold of a rule
+	COMPARISON_GT  = "gt"
+	COMPARISON_GTE = "gte"
+	COMPARISON_LT  = "lt"
+	COMPARISON_LTE = "lte"
+	COMPARISON_EQ  = "eq"
+	COMPARISON_NE  = "ne"
+
+	// State of a rule for a series, an event is formed only when it changes
+	RULE_STATE_FIRING   = "firing"
+	RULE_STATE_RESOLVED = "resolved"
+	RULE_STATE_EXPIRED  = "expired" // a firing series which hasn't reported for metrics.stale_after
+
+	DEFAULT_METRIC_STALE_AFTER = 5 * time.Minute
+
+	// Keys of the log body formed from a data point, mapping reads them as `body.<key>`
+	METRIC_FIELD_EVENT_ID   = "event_id" // hash of the series, state and the time of the data point
+	METRIC_FIELD_RULE       = "rule"
+	METRIC_FIELD_METRIC     = "metric"
+	METRIC_FIELD_UNIT       = "unit"
+	METRIC_FIELD_VALUE      = "value"
+	METRIC_FIELD_COMPARISON = "comparison"
+	METRIC_FIELD_THRESHOLD  = "threshold"
+	METRIC_FIELD_STATE      = "state"
+	METRIC_FIELD_TIME       = "time"
+	METRIC_FIELD_EVENT      = "event" // `metric.` and the state, ex: `metric.firing`
+)
+
+/*
+MetricsConfig has the rules which are evaluated on gauge and sum data points when the processor is in a
+metrics pipeline, metrics themselves are passed on as they are
+*/
+type MetricsConfig struct {
+	Rules      []MetricRule             `mapstructure:"rules"`
+	Mapping    cloudevent.MappingConfig `mapstructure:"mapping"`
+	StaleAfter time.Duration            `mapstructure:"stale_after"` // a firing series which doesn't report for this long expires
+}
+
+// MetricRule fires for a data point of the metric which has the attributes and whose value crosses the threshold
+type MetricRule struct {
+	Name       string                  `mapstructure:"name"`
+	Metric     string                  `mapstructure:"metric"`
+	Attributes []cloudevent.FilterRule `mapstructure:"attributes"` // all of them have to match the data point, fields same as mapping
+	Comparison string                  `mapstructure:"comparison"` // gt, gte, lt, lte, eq or ne
+	Threshold  float64                 `mapstructure:"threshold"`
+}
+
+type metricRule struct {
+	name       string
+	attributes []cloudevent.Matcher
+	comparison string
+	threshold  float64
+}
+
+/*
+metricConverter evaluates the rules and sends a CloudEvent when a series starts firing and when it's resolved
+Only the firing series are remembered, a series which stops reporting while firing expires after staleAfter
+*/
+type metricConverter struct {
+	*eventSender
+	rules      map[string][]metricRule // keyed by metric name
+	staleAfter time.Duration
+	now        func() time.Time
+
+	// Held while the events are sent, the state only changes once they are
+	lock   sync.Mutex
+	firing map[string]*firingSeries // keyed by seriesKey
+
+	stopExpiry chan struct{}
+	expiryDone sync.WaitGroup
+}
+
+// firingSeries is a series which is firing, its event is sent again as expired once it stops reporting
+type firingSeries struct {
+	lastSeen time.Time
+	event    plog.ResourceLogs // the firing event with its resource and scope
+}
+
+// Series whose state changes with the events of an evaluation, nil for the ones which aren't firing anymore
+type seriesChanges map[string]*firingSeries
+
+/*
+Data keys of the metric CloudEvents when nothing is configured in metrics.mapping, attributes of the data point
+are added under `attributes`
+*/
+func defaultMetricDataMapping() []cloudevent.DataFieldMapping {
+	return []cloudevent.DataFieldMapping{
+		{Key: METRIC_FIELD_RULE, From: cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_RULE},
+		{Key: METRIC_FIELD_METRIC, From: cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_METRIC},
+		{Key: METRIC_FIELD_UNIT, From: cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_UNIT},
+		{Key: METRIC_FIELD_VALUE, From: cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_VALUE},
+		{Key: METRIC_FIELD_COMPARISON, From: cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_COMPARISON},
+		{Key: METRIC_FIELD_THRESHOLD, From: cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_THRESHOLD},
+		{Key: METRIC_FIELD_STATE, From: cloudevent.FIELD_PREFIX_BODY + METRIC_FIELD_STATE},
+	}
+}
+
+// Validate checks the rules and the mapping, mapping is checked with the default data keys if there aren't any
+func (cfg *MetricsConfig) Validate() error {
+	if _, err := newMetricRules(cfg.Rules); err != nil {
+		return err
+	}
+
+	if cfg.StaleAfter < 0 {
+		return fmt.Errorf("metrics.stale_after can not be negative, provided: %s", cfg.StaleAfter)
+	}
+
+	mappingCfg := cfg.Mapping
+	if len(mappingCfg.Data) == 0 {
+		mappingCfg.Data = defaultMetricDataMapping()
+	}
+
+	if err := mappingCfg.Validate(); err != nil {
+		return fmt.Errorf("metrics: %w", err)
+	}
+
+	return nil
+}
+
+func newMetricRules(rules []MetricRule) (map[string][]metricRule, error) {
+	ret := make(map[string][]metricRule, len(rules))
+	names := make(map[string]bool, len(rules))
+
+	for i, r := range rules {
+		if len(r.Name) == 0 || len(r.Metric) == 0 {
+			return nil, fmt.Errorf("metrics.rules[%d] needs both name and metric, provided: name '%s' metric '%s'", i, r.Name, r.Metric)
+		}
+
+		if names[r.Name] {
+			return nil, fmt.Errorf("metrics.rules name '%s' is provided more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		switch r.Comparison {
+		case COMPARISON_GT, COMPARISON_GTE, COMPARISON_LT, COMPARISON_LTE, COMPARISON_EQ, COMPARISON_NE:
+		default:
+			return nil, fmt.Errorf("metrics.rules[%d] comparison should be one of '%s', '%s', '%s', '%s', '%s' or '%s', provided: %s",
+				i, COMPARISON_GT, COMPARISON_GTE, COMPARISON_LT, COMPARISON_LTE, COMPARISON_EQ, COMPARISON_NE, r.Comparison)
+		}
+
+		attributes, err := cloudevent.NewMatchers("attributes", r.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("metrics.rules[%d]: %w", i, err)
+		}
+
+		ret[r.Metric] = append(ret[r.Metric], metricRule{
+			name:       r.Name,
+			attributes: attributes,
+			comparison: r.Comparison,
+			threshold:  r.Threshold,
+		})
+	}
+
+	return ret, nil
+}
+
+func newMetricConverter(set component.TelemetrySettings, cfg *Config) (*metricConverter, error) {
+	rules, err := newMetricRules(cfg.Metrics.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return nil, errors.New("metrics.rules can not be empty when metrics are converted")
+	}
+
+	sender, err := newEventSender(set, cfg, cfg.eventsConfig(cloudevent.FiltersConfig{}, cfg.Metrics.Mapping, defaultMetricDataMapping()))
+	if err != nil {
+		return nil, err
+	}
+
+	staleAfter := cfg.Metrics.StaleAfter
+	if staleAfter == 0 {
+		staleAfter = DEFAULT_METRIC_STALE_AFTER
+	}
+
+	return &metricConverter{
+		eventSender: sender,
+		rules:       rules,
+		staleAfter:  staleAfter,
+		now:         time.Now,
+		firing:      make(map[string]*firingSeries),
+	}, nil
+}
+
+// Starts the output and the expiry of the firing series, which is checked every half of staleAfter
+func (c *metricConverter) start(ctx context.Context, host component.Host) error {
+	if err := c.eventSender.start(ctx, host); err != nil {
+		return err
+	}
+
+	c.stopExpiry = make(chan struct{})
+	c.expiryDone.Add(1)
+	go func() {
+		defer c.expiryDone.Done()
+
+		ticker := time.NewTicker(c.staleAfter / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.expireStale(context.Background())
+			case <-c.stopExpiry:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *metricConverter) shutdown(context.Context) error {
+	if c.stopExpiry != nil {
+		close(c.stopExpiry)
+		c.expiryDone.Wait()
+		c.stopExpiry = nil
+	}
+	return nil
+}
+
+// Evaluates the rules and sends the events to the output exporters, metrics are passed on as they are
+func (c *metricConverter) processMetrics(ctx context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	changes := seriesChanges{}
+	c.sendChanges(ctx, c.evaluate(md, changes), changes)
+	return md, nil
+}
+
+// Sends the expired events of the firing series which haven't reported for staleAfter
+func (c *metricConverter) expireStale(ctx context.Context) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ld := plog.NewLogs()
+	changes := seriesChanges{}
+	c.expire(ld, changes, c.now())
+	c.sendChanges(ctx, ld, changes)
+}
+
+/*
+Changes the state of the series once their events are sent. If they can't be sent the state is kept as it was,
+so that the same crossings (or expiries) form the events again with the next data points
+*/
+func (c *metricConverter) sendChanges(ctx context.Context, ld plog.Logs, changes seriesChanges) {
+	if ld.LogRecordCount() > 0 && c.send(ctx, ld, "metrics") != nil {
+		return
+	}
+
+	for key, series := range changes {
+		if series == nil {
+			delete(c.firing, key)
+		} else {
+			c.firing[key] = series
+		}
+	}
+}
+
+/*
+Forms a log for every series whose state has changed, resource and scope are the same as the metric
+The changes are put in changes and not in the firing series, c.lock has to be held
+*/
+func (c *metricConverter) evaluate(md pmetric.Metrics, changes seriesChanges) plog.Logs {
+	ld := plog.NewLogs()
+	now := c.now()
+	observed := pcommon.NewTimestampFromTime(now)
+
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		resourceKey := ""
+		rl := plog.NewResourceLogs()
+
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			sl := plog.NewScopeLogs()
+
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				metric := sm.Metrics().At(k)
+				rules := c.rules[metric.Name()]
+				if len(rules) == 0 {
+					continue
+				}
+
+				var points pmetric.NumberDataPointSlice
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					points = metric.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					points = metric.Sum().DataPoints()
+				default:
+					continue
+				}
+
+				if len(resourceKey) == 0 {
+					resourceKey = attributesKey(rm.Resource().Attributes())
+				}
+
+				for l := 0; l < points.Len(); l++ {
+					dp := points.At(l)
+
+					// Attributes are matched on a log with the attributes of the data point
+					lr := plog.NewLogRecord()
+					dp.Attributes().CopyTo(lr.Attributes())
+
+					for r := range rules {
+						if !rules[r].matches(rm.Resource(), lr) {
+							continue
+						}
+
+						key := seriesKey(rules[r].name, metric.Name(), resourceKey, dp.Attributes())
+						state, changed := c.updateState(changes, key, &rules[r], dataPointValue(dp), now)
+						if changed {
+							event := sl.LogRecords().AppendEmpty()
+							metricToLog(&rules[r], metric, dp, key, state, event, observed)
+							if state == RULE_STATE_FIRING {
+								changes[key].event = firingEvent(rm.Resource(), sm.Scope(), event)
+							}
+						}
+					}
+				}
+			}
+
+			if sl.LogRecords().Len() > 0 {
+				sm.Scope().CopyTo(sl.Scope())
+				sl.MoveTo(rl.ScopeLogs().AppendEmpty())
+			}
+		}
+
+		if rl.ScopeLogs().Len() > 0 {
+			rm.Resource().CopyTo(rl.Resource())
+			rl.MoveTo(ld.ResourceLogs().AppendEmpty())
+		}
+	}
+
+	return ld
+}
+
+/*
+Forgets the firing series which haven't reported for staleAfter, so that series which are gone (ex: deleted pods)
+aren't remembered forever, and adds their firing event to ld with the state changed to expired
+*/
+func (c *metricConverter) expire(ld plog.Logs, changes seriesChanges, now time.Time) {
+	observed := pcommon.NewTimestampFromTime(now)
+
+	for key, series := range c.firing {
+		if now.Sub(series.lastSeen) < c.staleAfter {
+			continue
+		}
+		changes[key] = nil
+
+		rl := ld.ResourceLogs().AppendEmpty()
+		series.event.CopyTo(rl)
+		lr := rl.ScopeLogs().At(0).LogRecords().At(0)
+		lr.SetTimestamp(observed)
+		lr.SetObservedTimestamp(observed)
+
+		body := lr.Body().Map()
+		body.PutStr(METRIC_FIELD_EVENT_ID, eventID(key, RULE_STATE_EXPIRED, observed))
+		body.PutStr(METRIC_FIELD_STATE, RULE_STATE_EXPIRED)
+		body.PutStr(METRIC_FIELD_TIME, now.UTC().Format(time.RFC3339Nano))
+		body.PutStr(METRIC_FIELD_EVENT, "metric."+RULE_STATE_EXPIRED)
+	}
+}
+
+// Keeps a copy of the firing event of a series for when it expires
+func firingEvent(resource pcommon.Resource, scope pcommon.InstrumentationScope, lr plog.LogRecord) plog.ResourceLogs {
+	rl := plog.NewResourceLogs()
+	resource.CopyTo(rl.Resource())
+	sl := rl.ScopeLogs().AppendEmpty()
+	scope.CopyTo(sl.Scope())
+	lr.CopyTo(sl.LogRecords().AppendEmpty())
+	return rl
+}
+
+// All the attribute rules have to match
+func (r *metricRule) matches(res pcommon.Resource, lr plog.LogRecord) bool {
+	for i := range r.attributes {
+		if !r.attributes[i].Matches(res, lr) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Returns the state of the series and true if it has changed, the change is put in changes. A series which keeps
+firing is only seen again, that doesn't need an event
+*/
+func (c *metricConverter) updateState(changes seriesChanges, key string, rule *metricRule, val float64, now time.Time) (string, bool) {
+	series, wasFiring := c.firing[key]
+	if changed, ok := changes[key]; ok {
+		series, wasFiring = changed, changed != nil
+	}
+
+	if compareValue(rule.comparison, val, rule.threshold) {
+		if wasFiring {
+			series.lastSeen = now
+			return RULE_STATE_FIRING, false
+		}
+		changes[key] = &firingSeries{lastSeen: now}
+		return RULE_STATE_FIRING, true
+	}
+
+	if !wasFiring {
+		return RULE_STATE_RESOLVED, false
+	}
+	changes[key] = nil
+	return RULE_STATE_RESOLVED, true
+}
+
+func metricToLog(rule *metricRule, metric pmetric.Metric, dp pmetric.NumberDataPoint, key string, state string, lr plog.LogRecord, observed pcommon.Timestamp) {
+	lr.SetTimestamp(dp.Timestamp())
+	lr.SetObservedTimestamp(observed)
+	dp.Attributes().CopyTo(lr.Attributes())
+
+	body := lr.Body().SetEmptyMap()
+	body.EnsureCapacity(10)
+	body.PutStr(METRIC_FIELD_EVENT_ID, eventID(key, state, dp.Timestamp()))
+	body.PutStr(METRIC_FIELD_RULE, rule.name)
+	body.PutStr(METRIC_FIELD_METRIC, metric.Name())
+	body.PutStr(METRIC_FIELD_UNIT, metric.Unit())
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		body.PutInt(METRIC_FIELD_VALUE, dp.IntValue())
+	} else {
+		body.PutDouble(METRIC_FIELD_VALUE, dp.DoubleValue())
+	}
+	body.PutStr(METRIC_FIELD_COMPARISON, rule.comparison)
+	body.PutDouble(METRIC_FIELD_THRESHOLD, rule.threshold)
+	body.PutStr(METRIC_FIELD_STATE, state)
+	body.PutStr(METRIC_FIELD_TIME, dp.Timestamp().AsTime().UTC().Format(time.RFC3339Nano))
+	body.PutStr(METRIC_FIELD_EVENT, "metric."+state)
+}
+
+// Hash of the series, state and the time, so that the same crossing gets the same id when it's sent again
+func eventID(key string, state string, ts pcommon.Timestamp) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	_, _ = hash.Write([]byte(state))
+	_, _ = hash.Write(strconv.AppendUint(nil, uint64(ts), 10))
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+func dataPointValue(dp pmetric.NumberDataPoint) float64 {
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		return float64(dp.IntValue())
+	}
+	return dp.DoubleValue()
+}
+
+// NaN never crosses the threshold
+func compareValue(comparison string, val float64, threshold float64) bool {
+	if math.IsNaN(val) {
+		return false
+	}
+
+	switch comparison {
+	case COMPARISON_GT:
+		return val > threshold
+	case COMPARISON_GTE:
+		return val >= threshold
+	case COMPARISON_LT:
+		return val < threshold
+	case COMPARISON_LTE:
+		return val <= threshold
+	case COMPARISON_EQ:
+		return val == threshold
+	case COMPARISON_NE:
+		return val != threshold
+	}
+
+	return false
+}
+
+// Identifies a series of a rule, attributes are sorted so that their order doesn't matter
+func seriesKey(rule string, metric string, resourceKey string, attrs pcommon.Map) string {
+	return rule + "\x00" + metric + "\x00" + resourceKey + "\x00" + attributesKey(attrs)
+}
+
+func attributesKey(attrs pcommon.Map) string {
+	keys := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+
+	var ret strings.Builder
+	for _, k := range keys {
+		val, _ := attrs.Get(k)
+		ret.WriteString(k)
+		ret.WriteByte('=')
+		ret.WriteString(val.AsString())
+		ret.WriteByte('\x00')
+	}
+
+	return ret.String()
+}
@@ -0,0 +1,272 @@
+package cloudeventtransform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+// Gauge of container restarts with a data point for prod and dev namespaces
+func testMetrics(prod int64, dev int64, ts time.Time) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("k8s.cluster.name", "test")
+	metrics := rm.ScopeMetrics().AppendEmpty().Metrics()
+
+	metric := metrics.AppendEmpty()
+	metric.SetName("k8s.container.restarts")
+	metric.SetUnit("{restart}")
+	points := metric.SetEmptyGauge().DataPoints()
+	for ns, val := range map[string]int64{"prod": prod, "dev": dev} {
+		dp := points.AppendEmpty()
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		dp.SetIntValue(val)
+		dp.Attributes().PutStr("k8s.namespace.name", ns)
+	}
+
+	// Not evaluated as there aren't any rules for it
+	metrics.AppendEmpty().SetName("k8s.pod.phase")
+
+	return md
+}
+
+func startTestMetricsProcessor(t *testing.T, cfg *Config) (processor.Metrics, *consumertest.MetricsSink, *testLogsExporter) {
+	events := component.NewIDWithName("kafka", "events")
+	cfg.Output.Exporters = []component.ID{events}
+	host, sinks := newTestHost(events)
+
+	next := new(consumertest.MetricsSink)
+	p, err := NewFactory().CreateMetricsProcessor(context.Background(), processortest.NewNopCreateSettings(), cfg, next)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), host))
+	t.Cleanup(func() { require.NoError(t, p.Shutdown(context.Background())) })
+
+	return p, next, sinks[events]
+}
+
+func TestMetricThresholdCrossings(t *testing.T) {
+	cfg := testConfig()
+	cfg.Metrics.Rules = []MetricRule{{
+		Name:       "restarts",
+		Metric:     "k8s.container.restarts",
+		Attributes: []cloudevent.FilterRule{{Field: "k8s.namespace.name", Values: []string{"prod"}}},
+		Comparison: COMPARISON_GT,
+		Threshold:  3,
+	}}
+	p, next, events := startTestMetricsProcessor(t, cfg)
+
+	ts := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	states := func() []string {
+		var ret []string
+		for _, ld := range events.AllLogs() {
+			records := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+			for i := 0; i < records.Len(); i++ {
+				ret = append(ret, jsonBodyData(t, records.At(i))[METRIC_FIELD_STATE].(string))
+			}
+		}
+		return ret
+	}
+
+	// Dev is above the threshold all the time but doesn't have the attributes of the rule
+	for i, prod := range []int64{2, 5, 6, 1, 1, 4} {
+		require.NoError(t, p.ConsumeMetrics(context.Background(), testMetrics(prod, 10, ts.Add(time.Duration(i)*time.Minute))))
+	}
+	assert.Equal(t, 6, len(next.AllMetrics()))
+	assert.Equal(t, []string{RULE_STATE_FIRING, RULE_STATE_RESOLVED, RULE_STATE_FIRING}, states())
+
+	event := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(events.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Bytes().AsRaw(), &event))
+	assert.Equal(t, "com.company.event.v1.metric.firing", event["type"])
+	assert.Equal(t, "2023-03-01T10:01:00Z", event["time"])
+	assert.NotEmpty(t, event["id"])
+	assert.Equal(t, map[string]interface{}{
+		"rule":       "restarts",
+		"metric":     "k8s.container.restarts",
+		"unit":       "{restart}",
+		"value":      float64(5),
+		"comparison": COMPARISON_GT,
+		"threshold":  float64(3),
+		"state":      RULE_STATE_FIRING,
+		"attributes": map[string]interface{}{"k8s.namespace.name": "prod"},
+	}, event["data"])
+}
+
+// Converter which reads the time from now, the expiry isn't started so that it's only checked when the test does it
+func startTestMetricConverter(t *testing.T, cfg *Config, now *time.Time) (*metricConverter, *testLogsExporter) {
+	events := component.NewIDWithName("kafka", "events")
+	cfg.Output.Exporters = []component.ID{events}
+	host, sinks := newTestHost(events)
+
+	c, err := newMetricConverter(componenttest.NewNopTelemetrySettings(), cfg)
+	require.NoError(t, err)
+	c.now = func() time.Time { return *now }
+	require.NoError(t, c.eventSender.start(context.Background(), host))
+
+	return c, sinks[events]
+}
+
+func TestMetricSeriesExpiry(t *testing.T) {
+	cfg := testConfig()
+	cfg.Metrics.StaleAfter = time.Minute
+	cfg.Metrics.Rules = []MetricRule{{
+		Name:       "restarts",
+		Metric:     "k8s.container.restarts",
+		Comparison: COMPARISON_GT,
+		Threshold:  3,
+	}}
+	ts := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := ts
+	c, events := startTestMetricConverter(t, cfg, &now)
+
+	// Both namespaces fire, only dev keeps reporting
+	_, err := c.processMetrics(context.Background(), testMetrics(5, 5, ts))
+	require.NoError(t, err)
+	require.Equal(t, 2, events.LogRecordCount())
+
+	now = ts.Add(50 * time.Second)
+	md := testMetrics(5, 5, now)
+	md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+		ns, _ := dp.Attributes().Get("k8s.namespace.name")
+		return ns.Str() == "prod"
+	})
+	_, err = c.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+	require.Equal(t, 2, events.LogRecordCount())
+
+	now = ts.Add(70 * time.Second)
+	c.expireStale(context.Background())
+	require.Equal(t, 3, events.LogRecordCount())
+	expired := events.AllLogs()[1].ResourceLogs().At(0)
+	assert.Equal(t, "test", expired.Resource().Attributes().AsRaw()["k8s.cluster.name"])
+
+	event := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(expired.ScopeLogs().At(0).LogRecords().At(0).Body().Bytes().AsRaw(), &event))
+	assert.Equal(t, "com.company.event.v1.metric.expired", event["type"])
+	assert.Equal(t, "2023-03-01T10:01:10Z", event["time"])
+	data := event["data"].(map[string]interface{})
+	assert.Equal(t, RULE_STATE_EXPIRED, data["state"])
+	assert.Equal(t, map[string]interface{}{"k8s.namespace.name": "prod"}, data["attributes"])
+
+	// Expired series isn't remembered, it fires again when it's back
+	_, err = c.processMetrics(context.Background(), testMetrics(5, 5, now))
+	require.NoError(t, err)
+	assert.Equal(t, 4, events.LogRecordCount())
+}
+
+func TestMetricSeriesExpiryTicker(t *testing.T) {
+	cfg := testConfig()
+	cfg.Metrics.StaleAfter = 50 * time.Millisecond
+	cfg.Metrics.Rules = []MetricRule{{
+		Name:       "restarts",
+		Metric:     "k8s.container.restarts",
+		Comparison: COMPARISON_GT,
+		Threshold:  3,
+	}}
+	p, _, events := startTestMetricsProcessor(t, cfg)
+
+	// Nothing reports after the first data points, both series are expired without waiting for more metrics
+	require.NoError(t, p.ConsumeMetrics(context.Background(), testMetrics(5, 5, time.Now())))
+	require.Equal(t, 2, events.LogRecordCount())
+	require.Eventually(t, func() bool { return events.LogRecordCount() == 4 }, 5*time.Second, 10*time.Millisecond)
+
+	for _, ld := range events.AllLogs()[1:] {
+		records := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+		for i := 0; i < records.Len(); i++ {
+			assert.Equal(t, RULE_STATE_EXPIRED, jsonBodyData(t, records.At(i))[METRIC_FIELD_STATE])
+		}
+	}
+}
+
+func TestMetricStateAfterFailedSend(t *testing.T) {
+	cfg := testConfig()
+	cfg.Metrics.StaleAfter = time.Minute
+	cfg.Metrics.Rules = []MetricRule{{
+		Name:       "restarts",
+		Metric:     "k8s.container.restarts",
+		Comparison: COMPARISON_GT,
+		Threshold:  3,
+	}}
+	ts := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := ts
+	c, events := startTestMetricConverter(t, cfg, &now)
+
+	// The firing events aren't sent, so the series don't fire and the next data points form them again
+	events.err = errors.New("kafka is down")
+	_, err := c.processMetrics(context.Background(), testMetrics(5, 5, ts))
+	require.NoError(t, err)
+	assert.Empty(t, c.firing)
+
+	events.err = nil
+	_, err = c.processMetrics(context.Background(), testMetrics(5, 5, ts))
+	require.NoError(t, err)
+	assert.Equal(t, 2, events.LogRecordCount())
+	assert.Len(t, c.firing, 2)
+
+	// Same for the resolved events
+	events.err = errors.New("kafka is down")
+	_, err = c.processMetrics(context.Background(), testMetrics(1, 1, ts.Add(time.Second)))
+	require.NoError(t, err)
+	assert.Len(t, c.firing, 2)
+
+	// And the expired ones
+	now = ts.Add(2 * time.Minute)
+	c.expireStale(context.Background())
+	assert.Len(t, c.firing, 2)
+
+	events.err = nil
+	c.expireStale(context.Background())
+	assert.Equal(t, 4, events.LogRecordCount())
+	assert.Empty(t, c.firing)
+}
+
+func TestCompareValue(t *testing.T) {
+	assert.True(t, compareValue(COMPARISON_GT, 2, 1))
+	assert.False(t, compareValue(COMPARISON_GT, 1, 1))
+	assert.True(t, compareValue(COMPARISON_GTE, 1, 1))
+	assert.True(t, compareValue(COMPARISON_LT, 0.5, 1))
+	assert.True(t, compareValue(COMPARISON_LTE, 1, 1))
+	assert.True(t, compareValue(COMPARISON_EQ, 1, 1))
+	assert.True(t, compareValue(COMPARISON_NE, 2, 1))
+	assert.False(t, compareValue(COMPARISON_NE, math.NaN(), 1))
+}
+
+func TestMetricRulesValidation(t *testing.T) {
+	_, err := NewFactory().CreateMetricsProcessor(context.Background(), processortest.NewNopCreateSettings(), testConfig(), new(consumertest.MetricsSink))
+	assert.Error(t, err)
+
+	rule := MetricRule{Name: "restarts", Metric: "k8s.container.restarts", Comparison: COMPARISON_GT}
+	tests := []MetricRule{
+		{Metric: rule.Metric, Comparison: COMPARISON_GT},
+		{Name: rule.Name, Metric: rule.Metric, Comparison: ">"},
+		{Name: rule.Name, Metric: rule.Metric, Comparison: COMPARISON_GT, Attributes: []cloudevent.FilterRule{{Field: "k8s.namespace.name"}}},
+	}
+
+	for _, tt := range tests {
+		cfg := testConfig()
+		cfg.Metrics.Rules = []MetricRule{tt}
+		assert.Error(t, cfg.Validate())
+	}
+
+	cfg := testConfig()
+	cfg.Metrics.Rules = []MetricRule{rule, rule}
+	assert.Error(t, cfg.Validate())
+
+	cfg = testConfig()
+	cfg.Metrics.Rules = []MetricRule{rule}
+	cfg.Metrics.StaleAfter = -time.Minute
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,150 @@
+package cloudeventtransform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+/*
+OutputConfig lists the logs exporters which receive the CloudEvents formed from traces and metrics,
+they work like a connector as the events leave the pipeline they were formed in. Exporters have to be
+part of a logs pipeline for the collector to create them
+*/
+type OutputConfig struct {
+	Exporters []component.ID `mapstructure:"exporters"`
+}
+
+// eventOutput sends the CloudEvent log records to the configured exporters
+type eventOutput struct {
+	ids       []component.ID
+	consumers []consumer.Logs
+}
+
+/*
+eventSender converts the logs formed from spans or metrics to CloudEvents with the same code as logs
+and sends them to the output exporters
+*/
+type eventSender struct {
+	events *cloudeventTransformProcessor
+	logger *zap.Logger
+	output *eventOutput
+}
+
+/*
+Returns the configuration which converts the logs formed from spans or metrics, everything other than
+filters and mapping is the same as logs
+*/
+func (cfg *Config) eventsConfig(filters cloudevent.FiltersConfig, mapping cloudevent.MappingConfig, defaultData []cloudevent.DataFieldMapping) *Config {
+	eventsCfg := *cfg
+	eventsCfg.Filter = "*"
+	eventsCfg.Filters = filters
+	eventsCfg.Mapping = mapping
+
+	if len(eventsCfg.Mapping.Data) == 0 {
+		eventsCfg.Mapping.Data = defaultData
+	}
+
+	// A log which isn't converted can't be sent anywhere
+	if eventsCfg.OnMissingAttributes == cloudevent.MISSING_ATTR_PASSTHROUGH {
+		eventsCfg.OnMissingAttributes = cloudevent.MISSING_ATTR_DROP
+	}
+
+	return &eventsCfg
+}
+
+func newEventSender(set component.TelemetrySettings, cfg *Config, eventsCfg *Config) (*eventSender, error) {
+	events, err := newProcessor(set, eventsCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := newEventOutput(&cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventSender{
+		events: events,
+		logger: set.Logger,
+		output: output,
+	}, nil
+}
+
+func (s *eventSender) start(_ context.Context, host component.Host) error {
+	return s.output.start(host)
+}
+
+/*
+Converts the logs and sends them to the output exporters, failures are only logged and returned as the spans or
+metrics they came from shouldn't be retried or dropped because of the events
+*/
+func (s *eventSender) send(ctx context.Context, ld plog.Logs, from string) error {
+	if err := converRawMsgtToCloudEvent(ctx, s.events, &ld); err != nil {
+		s.logger.Error("Couldn't convert "+from+" to CloudEvents", zap.Error(err))
+		return err
+	}
+
+	if ld.LogRecordCount() > 0 {
+		if err := s.output.consume(ctx, ld); err != nil {
+			s.logger.Error("Couldn't send CloudEvents formed from "+from, zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newEventOutput(cfg *OutputConfig) (*eventOutput, error) {
+	if len(cfg.Exporters) == 0 {
+		return nil, errors.New("output.exporters can not be empty when traces or metrics are converted")
+	}
+
+	return &eventOutput{ids: cfg.Exporters}, nil
+}
+
+// Looks up the exporters, they're only available once the components have started
+func (o *eventOutput) start(host component.Host) error {
+	exporters := host.GetExporters()[component.DataTypeLogs]
+
+	o.consumers = make([]consumer.Logs, 0, len(o.ids))
+	for _, id := range o.ids {
+		exp, ok := exporters[id]
+		if !ok {
+			return fmt.Errorf("output exporter '%s' is not part of any logs pipeline", id)
+		}
+
+		logsConsumer, ok := exp.(consumer.Logs)
+		if !ok {
+			return fmt.Errorf("output exporter '%s' can not consume logs", id)
+		}
+		o.consumers = append(o.consumers, logsConsumer)
+	}
+
+	return nil
+}
+
+// Sends the logs to every exporter, each of them gets its own copy except the last one
+func (o *eventOutput) consume(ctx context.Context, ld plog.Logs) error {
+	var err error
+
+	for i, c := range o.consumers {
+		logs := ld
+		if i < len(o.consumers)-1 {
+			logs = plog.NewLogs()
+			ld.CopyTo(logs)
+		}
+
+		if cErr := c.ConsumeLogs(ctx, logs); cErr != nil && err == nil {
+			err = fmt.Errorf("output exporter '%s' failed: %w", o.ids[i], cErr)
+		}
+	}
+
+	return err
+}
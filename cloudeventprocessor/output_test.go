@@ -0,0 +1,76 @@
+package cloudeventtransform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// Logs exporter which keeps everything it receives, or fails with err when it's set
+type testLogsExporter struct {
+	component.StartFunc
+	component.ShutdownFunc
+	*consumertest.LogsSink
+	err error
+}
+
+func (e *testLogsExporter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.LogsSink.ConsumeLogs(ctx, ld)
+}
+
+// Host which has the given logs exporters
+type testHost struct {
+	component.Host
+	exporters map[component.ID]component.Component
+}
+
+func (h *testHost) GetExporters() map[component.DataType]map[component.ID]component.Component {
+	return map[component.DataType]map[component.ID]component.Component{component.DataTypeLogs: h.exporters}
+}
+
+func newTestHost(ids ...component.ID) (*testHost, map[component.ID]*testLogsExporter) {
+	host := &testHost{Host: componenttest.NewNopHost(), exporters: map[component.ID]component.Component{}}
+	sinks := map[component.ID]*testLogsExporter{}
+
+	for _, id := range ids {
+		sinks[id] = &testLogsExporter{LogsSink: new(consumertest.LogsSink)}
+		host.exporters[id] = sinks[id]
+	}
+
+	return host, sinks
+}
+
+func TestEventOutput(t *testing.T) {
+	kafka := component.NewIDWithName("kafka", "events")
+	knative := component.NewID("knative")
+	host, sinks := newTestHost(kafka, knative)
+
+	_, err := newEventOutput(&OutputConfig{})
+	assert.Error(t, err)
+
+	o, err := newEventOutput(&OutputConfig{Exporters: []component.ID{component.NewID("missing")}})
+	require.NoError(t, err)
+	assert.Error(t, o.start(host))
+
+	o, err = newEventOutput(&OutputConfig{Exporters: []component.ID{kafka, knative}})
+	require.NoError(t, err)
+	require.NoError(t, o.start(host))
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("event")
+	require.NoError(t, o.consume(context.Background(), ld))
+
+	for _, id := range []component.ID{kafka, knative} {
+		require.Len(t, sinks[id].AllLogs(), 1)
+		assert.Equal(t, "event", sinks[id].AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+	}
+}
@@ -0,0 +1,78 @@
+package cloudeventtransform
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	ATTR_OBJECT_UID = "k8s.object.uid"
+
+	// Attribute of the CloudEvents partitioning extension
+	EXTENSION_PARTITION_KEY = "partitionkey"
+
+	// Log attribute which gets the partition key by default, same as the attribute of binary mode
+	// so that exporters (like kafka) can use it as the message key in both modes
+	ATTR_PARTITION_KEY = ATTR_CE_PREFIX + EXTENSION_PARTITION_KEY
+
+	// Partition key when nothing is configured, ex: `testns/6a0c1f2e-...` or just the uid for cluster
+	// scoped objects, left out when the log isn't about a k8s object
+	DEFAULT_PARTITION_KEY_TEMPLATE = `{{if .Attr "k8s.object.uid"}}{{with .Attr "k8s.namespace.name"}}{{.}}/{{end}}{{.Attr "k8s.object.uid"}}{{end}}`
+)
+
+// partitionKeyBuilder forms the partition key which keeps the events of the same object in order
+type partitionKeyBuilder struct {
+	types     *cloudevent.TypeBuilder // gives the rest of the template context
+	tmpl      *template.Template      // nil when the default layout is used
+	attribute string                  // log attribute which gets the key, not set when empty
+}
+
+// Returns nil if partition_key_template is empty, the extension is left out then
+func newPartitionKeyBuilder(spec *CloudEventSpec, attribute string, types *cloudevent.TypeBuilder) (*partitionKeyBuilder, error) {
+	if len(spec.PartitionKeyTemplate) == 0 {
+		return nil, nil
+	}
+
+	b := &partitionKeyBuilder{types: types, attribute: attribute}
+	if spec.PartitionKeyTemplate == DEFAULT_PARTITION_KEY_TEMPLATE {
+		return b, nil
+	}
+
+	var err error
+	b.tmpl, err = cloudevent.ParseTemplate("partition_key_template", spec.PartitionKeyTemplate)
+	return b, err
+}
+
+// Forms the partition key of the CloudEvent, empty if the template gives nothing
+func (b *partitionKeyBuilder) build(m *cloudevent.Mapping, res pcommon.Resource, lr plog.LogRecord, ev *cloudeventdata) (string, error) {
+	if b.tmpl == nil {
+		return k8sObjectPartitionKey(res, lr), nil
+	}
+
+	var ret strings.Builder
+	if err := b.tmpl.Execute(&ret, b.types.Context(m, res, lr, &ev.Event)); err != nil {
+		return "", err
+	}
+
+	return ret.String(), nil
+}
+
+// Same as DEFAULT_PARTITION_KEY_TEMPLATE without executing the template
+func k8sObjectPartitionKey(res pcommon.Resource, lr plog.LogRecord) string {
+	ctx := cloudevent.RecordContext(res, lr)
+
+	uid := ctx.Attr(ATTR_OBJECT_UID)
+	if len(uid) == 0 {
+		return ""
+	}
+
+	if ns := ctx.Attr(cloudevent.ATTR_EVENT_NS); len(ns) > 0 {
+		return ns + "/" + uid
+	}
+
+	return uid
+}
@@ -0,0 +1,64 @@
+package cloudeventtransform
+
+import (
+	"testing"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestPartitionKeyBuilder(t *testing.T) {
+	types, err := cloudevent.NewTypeBuilder("", "com.acme.k8s", "v1", "", "")
+	require.NoError(t, err)
+	defaultTmpl, err := cloudevent.ParseTemplate("partition_key_template", DEFAULT_PARTITION_KEY_TEMPLATE)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		attrs    map[string]string
+		expected string
+	}{
+		{name: "namespaced", attrs: map[string]string{cloudevent.ATTR_EVENT_NS: "testns", ATTR_OBJECT_UID: "6a0c1f2e"}, expected: "testns/6a0c1f2e"},
+		{name: "cluster scoped", attrs: map[string]string{ATTR_OBJECT_UID: "6a0c1f2e"}, expected: "6a0c1f2e"},
+		{name: "not an object", attrs: map[string]string{cloudevent.ATTR_EVENT_NS: "testns"}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := pcommon.NewResource()
+			lr := plog.NewLogRecord()
+			for k, v := range tt.attrs {
+				res.Attributes().PutStr(k, v)
+			}
+
+			b, err := newPartitionKeyBuilder(&CloudEventSpec{PartitionKeyTemplate: DEFAULT_PARTITION_KEY_TEMPLATE}, ATTR_PARTITION_KEY, types)
+			require.NoError(t, err)
+			key, err := b.build(nil, res, lr, &cloudeventdata{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, key)
+
+			b.tmpl = defaultTmpl
+			key, err = b.build(nil, res, lr, &cloudeventdata{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, key)
+		})
+	}
+
+	b, err := newPartitionKeyBuilder(&CloudEventSpec{PartitionKeyTemplate: `{{.Attr "k8s.object.kind"}}/{{.Subject}}`}, "", types)
+	require.NoError(t, err)
+	res := pcommon.NewResource()
+	res.Attributes().PutStr(cloudevent.ATTR_OBJECT_KIND, "Pod")
+	key, err := b.build(nil, res, plog.NewLogRecord(), &cloudeventdata{Event: cloudevent.Event{Subject: "pod-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Pod/pod-1", key)
+
+	b, err = newPartitionKeyBuilder(&CloudEventSpec{}, ATTR_PARTITION_KEY, types)
+	require.NoError(t, err)
+	assert.Nil(t, b)
+
+	_, err = newPartitionKeyBuilder(&CloudEventSpec{PartitionKeyTemplate: "{{.Attr"}, ATTR_PARTITION_KEY, types)
+	assert.Error(t, err)
+}
@@ -4,59 +4,129 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
-	"unicode"
+	"sync"
 
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 const (
 	//CE_BODY           = `{"datacontenttype":"application/json; charset=utf-8","id":"%s","source":"%s","specversion":"%s","type":"%s","data":%s}`
 	//CE_DATA_META_BODY = `{"reason":"%s","start_time":"%s","name":"%s","uid":"%s","namespace":"%s","count":%s,"message":"%s"}`
 
-	ATTR_EVENT_COUNT      = "k8s.event.count"
-	ATTR_EVENT_NAME       = "k8s.event.name"
-	ATTR_EVENT_NS         = "k8s.namespace.name"
-	ATTR_EVENT_REASON     = "k8s.event.reason"
-	ATTR_EVENT_START_TIME = "k8s.event.start_time"
-	ATTR_EVENT_UID        = "k8s.event.uid"
-
 	FETCH_ATTR = true
 
-	BACKSLASH_BYTE   = byte('\\')
-	CLOSE_BRACE_BYTE = byte('}')
-	COLON_BYTE       = byte(':')
-	COMMA_BYTE       = byte(',')
-	OPEN_BRACE_BYTE  = byte('{')
-	QUOTE_BYTE       = byte('"')
-)
-
-var (
-	filters        []string         // k8s.event.reason filters
-	filterAllowAll bool     = false // if configuration changes this to true, it'll let pass all of the logs
-
-	typeVersion string // typeverson will define the body type of CloudEvent (right now it's v1 specific)
+	// Content modes, structured puts the whole CloudEvent in the body while
+	// binary keeps only the data in body and moves the context attributes to log attributes
+	MODE_STRUCTURED = "structured"
+	MODE_BINARY     = "binary"
+	MODE_BATCH      = "batch" // structured events of a ScopeLogs are collapsed into a JSON array, see BatchConfig
+
+	// Log attributes that carry the context attributes in binary mode, prefix is the
+	// same as kafka protocol binding headers so that exporters can map them as is
+	ATTR_CE_PREFIX      = "ce_"
+	ATTR_CE_ID          = ATTR_CE_PREFIX + "id"
+	ATTR_CE_SOURCE      = ATTR_CE_PREFIX + "source"
+	ATTR_CE_SPECVERSION = ATTR_CE_PREFIX + "specversion"
+	ATTR_CE_TYPE        = ATTR_CE_PREFIX + "type"
+	ATTR_CE_TIME        = ATTR_CE_PREFIX + "time"
+	ATTR_CE_SUBJECT     = ATTR_CE_PREFIX + "subject"
+	ATTR_CONTENT_TYPE   = "content-type"
+
+	CONTENT_TYPE_JSON         = "application/json; charset=utf-8"
+	CONTENT_TYPE_OCTET_STREAM = "application/octet-stream" // bytes data from mapping.data_from
+
+	// What's done with a prepared record when the batch is converted
+	RECORD_CONVERT     = 0
+	RECORD_DROP        = 1 // on_missing_attributes `drop` or a duplicate which is dropped
+	RECORD_PASSTHROUGH = 2 // on_missing_attributes `passthrough`, left as it is
+
+	MAX_POOLED_RECORDS = 8192
 )
 
 type cloudeventTransformProcessor struct {
-	id          string
-	source      string
-	specversion string
-	typ         string
+	id                  string
+	filters             []string // k8s.event.reason filters
+	filterAllowAll      bool
+	logFilter           *cloudevent.LogFilter // filters rules, nil if there aren't any
+	mapping             *cloudevent.Mapping
+	mode                string
+	batch               BatchConfig
+	format              string // json or protobuf, structured events and batches are encoded in it
+	onMissingAttributes string
+	source              string
+	spec                *cloudevent.SpecVersion
+	dataSchema          string                     // URI written under the attribute of the spec version, left out when empty
+	extensions          []cloudevent.Extension     // ce.extensions, their values are written after the context attributes
+	subjectBuilder      *cloudevent.SubjectBuilder // nil if mapping.subject is set or subject_template is empty
+	partitionKeyBuilder *partitionKeyBuilder       // nil if partition_key_template is empty
+	dedup               *deduplicator              // nil if dedup isn't enabled
+	encoder             *envelopeEncoder           // escaped parts of the envelope which are the same for every event
+	redactor            *cloudevent.Redactor       // nil if redaction doesn't have any detectors or rules
+	decoder             *cloudeventDecoder         // nil unless the direction is decode, nothing else is set then
+	telemetry           *processorTelemetry
+	timeResolver        *cloudevent.TimeResolver
+	typeBuilder         *cloudevent.TypeBuilder
 }
 
 type cloudeventdata struct {
-	count     int
-	message   string
-	name      string
-	namespace string
-	reason    string
-	startTime string
-	uid       string // This field will be converted and passed to cloudeventTransformProcessor.id
+	cloudevent.Event
+	time         string   // RFC 3339, empty if the log doesn't have it
+	typ          string   // CloudEvent type formed by type_template
+	extensions   []string // Values of ce.extensions in the same order, empty ones are left out
+	traceparent  string   // Trace context of the log, empty if it doesn't have one
+	partitionKey string   // Formed by partition_key_template, left out when empty
+}
+
+/*
+preparedRecord is a log record of a batch which has been formed into a CloudEvent but isn't converted yet,
+records are converted only once every record of the batch is prepared
+*/
+type preparedRecord struct {
+	action    int  // RECORD_*
+	duplicate bool // Tagged with ATTR_DUPLICATE when it's converted
+	data      cloudeventdata
+}
+
+var preparedRecordsPool = sync.Pool{
+	New: func() interface{} {
+		return &[]preparedRecord{}
+	},
+}
+
+// Gets n prepared records, the values they keep from earlier batches get overwritten while preparing
+func getPreparedRecords(n int) *[]preparedRecord {
+	records := preparedRecordsPool.Get().(*[]preparedRecord)
+	if cap(*records) < n {
+		*records = make([]preparedRecord, n)
+	}
+	*records = (*records)[:n]
+	return records
+}
+
+// Puts the records back in the pool, large ones are left out so that a single big batch doesn't stay in memory
+func putPreparedRecords(records *[]preparedRecord) {
+	if cap(*records) > MAX_POOLED_RECORDS {
+		return
+	}
+
+	// Values point in the logs of the batch, they're let go so that the logs can be freed
+	for i := range *records {
+		data := &(*records)[i].data
+		for j := range data.Data {
+			data.Data[j] = pcommon.Value{}
+		}
+		for j := range data.Resource {
+			data.Resource[j] = pcommon.Value{}
+		}
+		data.Attributes = pcommon.Map{}
+	}
+	preparedRecordsPool.Put(records)
 }
 
 func newProcessor(set component.TelemetrySettings, cfg *Config) (*cloudeventTransformProcessor, error) {
@@ -68,15 +138,29 @@ func newProcessor(set component.TelemetrySettings, cfg *Config) (*cloudeventTran
 		return nil, err
 	}
 
+	if cfg.Direction == DIRECTION_DECODE {
+		telemetry, tErr := newProcessorTelemetry(set)
+		if tErr != nil {
+			return nil, tErr
+		}
+		return &cloudeventTransformProcessor{
+			decoder:   newCloudeventDecoder(cfg.OnDecodeError, telemetry),
+			telemetry: telemetry,
+		}, nil
+	}
+
+	// Every instance keeps its own filters so that multiple named instances don't affect each other
+	var filters []string
+	filterAllowAll := false // if configuration changes this to true, it'll let pass all of the logs
+
 	if len(cfg.Ce.AppendType) > 0 {
 		conf.Ce.AppendType = cfg.Ce.AppendType
 	}
 
 	if len(cfg.Ce.SpecVersion) > 0 {
 		conf.Ce.SpecVersion = cfg.Ce.SpecVersion
-
-		typeVersion = "v" + string(conf.Ce.SpecVersion[0])
 	}
+	spec := cloudevent.GetSpecVersion(conf.Ce.SpecVersion)
 
 	if len(cfg.Ce.Source) > 0 {
 		conf.Ce.Source = cfg.Ce.Source
@@ -97,10 +181,71 @@ func newProcessor(set component.TelemetrySettings, cfg *Config) (*cloudeventTran
 		}
 	}
 
+	if len(cfg.Mode) > 0 {
+		conf.Mode = cfg.Mode
+	}
+
+	if len(cfg.OnMissingAttributes) > 0 {
+		conf.OnMissingAttributes = cfg.OnMissingAttributes
+	}
+
+	conf.Ce.TypeTemplate = cfg.Ce.TypeTemplate
+	typeBuilder, bErr := cloudevent.NewTypeBuilder(conf.Ce.TypeTemplate, conf.Ce.AppendType, spec.TypeVersion, conf.Ce.SpecVersion, conf.Ce.Source)
+	if bErr != nil {
+		return nil, bErr
+	}
+
+	// A mapped subject takes precedence over the template
+	var subjectBuilder *cloudevent.SubjectBuilder
+	if len(cfg.Mapping.Subject) == 0 {
+		if subjectBuilder, bErr = cloudevent.NewSubjectBuilder(cfg.Ce.SubjectTemplate, typeBuilder); bErr != nil {
+			return nil, bErr
+		}
+	}
+
+	partitionKeyBuilder, bErr := newPartitionKeyBuilder(&cfg.Ce, cfg.PartitionKeyAttribute, typeBuilder)
+	if bErr != nil {
+		return nil, bErr
+	}
+
+	logFilter, fErr := cloudevent.NewLogFilter(&cfg.Filters)
+	if fErr != nil {
+		return nil, fErr
+	}
+
+	telemetry, tErr := newProcessorTelemetry(set)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	mapping := cloudevent.NewMapping(&cfg.Mapping, cfg.MissingAttributeDefaults)
+	if mapping.DataFrom == nil {
+		mapping.Resource = cloudevent.NewResourceDataFields(cfg.ResourceAttributes)
+	}
+
+	extensions := cloudevent.NewExtensions(cloudevent.WithResourceExtensions(cfg.Ce.Extensions, cfg.ResourceAttributes))
+
 	p := &cloudeventTransformProcessor{
-		source:      conf.Ce.Source,
-		specversion: conf.Ce.SpecVersion,
-		typ:         conf.Ce.AppendType,
+		filters:             filters,
+		filterAllowAll:      filterAllowAll,
+		logFilter:           logFilter,
+		mapping:             mapping,
+		mode:                conf.Mode,
+		batch:               cfg.Batch,
+		format:              cfg.Format,
+		onMissingAttributes: conf.OnMissingAttributes,
+		source:              conf.Ce.Source,
+		spec:                spec,
+		dataSchema:          cfg.Ce.DataSchema,
+		extensions:          extensions,
+		subjectBuilder:      subjectBuilder,
+		partitionKeyBuilder: partitionKeyBuilder,
+		dedup:               newDeduplicator(&cfg.Dedup),
+		encoder:             newEnvelopeEncoder(conf.Ce.Source, cfg.Ce.DataSchema, spec, extensions, mapping),
+		redactor:            cloudevent.NewRedactor(&cfg.Redaction, telemetry.recordRedactions),
+		telemetry:           telemetry,
+		timeResolver:        cloudevent.NewTimeResolver(cfg.Ce.TimeSource, cfg.Ce.OnInvalidTime, mapping),
+		typeBuilder:         typeBuilder,
 	}
 
 	return p, err
@@ -111,30 +256,26 @@ func (ce *cloudeventTransformProcessor) Capabilities() consumer.Capabilities {
 }
 
 func (ce *cloudeventTransformProcessor) processLogs(ctx context.Context, ld plog.Logs) (plog.Logs, error) {
-	return ld, converRawMsgtToCloudEvent(ce, &ld)
+	if ce.decoder != nil {
+		return ld, ce.decoder.decodeLogs(ctx, ld)
+	}
+	return ld, converRawMsgtToCloudEvent(ctx, ce, &ld)
 }
 
-func converRawMsgtToCloudEvent(ce *cloudeventTransformProcessor, ld *plog.Logs) error {
-	var cloudEventData cloudeventdata
+func converRawMsgtToCloudEvent(ctx context.Context, ce *cloudeventTransformProcessor, ld *plog.Logs) error {
+	ce.telemetry.recordIn(ctx, ld.LogRecordCount())
 
-	if !filterAllowAll {
+	if !ce.filterAllowAll || ce.logFilter != nil {
 		ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+			resource := rl.Resource()
+
 			rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
 				sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
-					reason, reasonOk := lr.Attributes().Get(ATTR_EVENT_REASON)
-					if !reasonOk {
-						return false
+					if reason := ce.filterReason(resource, lr); len(reason) > 0 {
+						ce.telemetry.recordFiltered(ctx, reason)
+						return true
 					}
-
-					reasonFound := false
-					for _, r := range filters {
-						if r == reason.AsString() {
-							reasonFound = true
-							break
-						}
-					}
-
-					return !reasonFound
+					return false
 				})
 				return sl.LogRecords().Len() == 0
 			})
@@ -142,210 +283,439 @@ func converRawMsgtToCloudEvent(ce *cloudeventTransformProcessor, ld *plog.Logs)
 		})
 	}
 
-	// Convert the log/s
-	for i := 0; i < ld.ResourceLogs().Len(); i++ {
-		scopeLogs := ld.ResourceLogs().At(i).ScopeLogs()
+	prepared := getPreparedRecords(ld.LogRecordCount())
+	defer putPreparedRecords(prepared)
 
-		for j := 0; j < scopeLogs.Len(); j++ {
-			logRecord := scopeLogs.At(j)
-			records := logRecord.LogRecords()
+	// Keys which are seen first in this batch, forgotten if the batch fails
+	var dedupKeys []string
 
+	/*
+		Every record is prepared before any of them is modified, so that a batch which fails is left as it was
+		(apart from the filtered out records) and a retry of it doesn't find records which are already converted
+	*/
+	n := 0
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			records := rl.ScopeLogs().At(j).LogRecords()
 			for k := 0; k < records.Len(); k++ {
-				//var cloudEventMetaData string
-				currentMessage := records.At(k).Body()
+				rec := &(*prepared)[n]
+				n++
 
-				// Get all the required attributes
-				if FETCH_ATTR {
-					attrMap := records.At(k).Attributes()
-
-					eventCount, eventCountOk := attrMap.Get(ATTR_EVENT_COUNT)
-					eventName, eventNameOk := attrMap.Get(ATTR_EVENT_NAME)
-					eventNs, eventNsOk := attrMap.Get(ATTR_EVENT_NS)
-					eventUid, eventUidOk := attrMap.Get(ATTR_EVENT_UID)
-					reason, reasonOk := attrMap.Get(ATTR_EVENT_REASON)
-					startTime, startTimeOk := attrMap.Get(ATTR_EVENT_START_TIME)
+				if err := ce.prepareLogRecord(ctx, rl.Resource(), records.At(k), rec, &dedupKeys); err != nil {
+					if ce.dedup != nil {
+						ce.dedup.forget(dedupKeys)
+					}
+					return err
+				}
+			}
+		}
+	}
 
-					anyError := !(reasonOk && startTimeOk && eventNameOk && eventUidOk && eventNsOk && eventCountOk)
+	// Convert the log/s, records are removed only if they're dropped by on_missing_attributes policy or are duplicates
+	n = 0
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			var batch *eventBatch
+			if ce.mode == MODE_BATCH {
+				batch = newEventBatch(&ce.batch, ce.format)
+			}
 
-					if anyError {
-						overAllErrStr := ""
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				rec := &(*prepared)[n]
+				n++
+				return ce.convertLogRecord(ctx, lr, rec, batch)
+			})
 
-						if !reasonOk {
-							overAllErrStr += "{" + ATTR_EVENT_REASON + "} "
-						}
+			if batch != nil {
+				batch.flush()
+			}
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
 
-						if !startTimeOk {
-							overAllErrStr += "{" + ATTR_EVENT_START_TIME + "} "
-						}
+	return nil
+}
 
-						if !eventNameOk {
-							overAllErrStr += "{" + ATTR_EVENT_NAME + "} "
-						}
+/*
+Checks the log against the `filter` reasons and the `filters` rules, both have to let it pass
+Logs which don't have k8s.event.reason aren't filtered by reasons. Returns why the log is
+filtered out (FILTER_REASON_*), empty if it passes
+*/
+func (ce *cloudeventTransformProcessor) filterReason(resource pcommon.Resource, lr plog.LogRecord) string {
+	if !ce.filterAllowAll {
+		if reason, reasonOk := lr.Attributes().Get(cloudevent.ATTR_EVENT_REASON); reasonOk {
+			reasonFound := false
+			for _, r := range ce.filters {
+				if r == reason.AsString() {
+					reasonFound = true
+					break
+				}
+			}
 
-						if !eventUidOk {
-							overAllErrStr += "{" + ATTR_EVENT_UID + "} "
-						}
+			if !reasonFound {
+				return FILTER_REASON_EVENT_REASON
+			}
+		}
+	}
 
-						if !eventNsOk {
-							overAllErrStr += "{" + ATTR_EVENT_NS + "} "
-						}
+	if ce.logFilter != nil && !ce.logFilter.Keep(resource, lr) {
+		return FILTER_REASON_RULES
+	}
 
-						if !eventCountOk {
-							overAllErrStr += "{" + ATTR_EVENT_COUNT + "} "
-						}
+	return ""
+}
 
-						return errors.New(fmt.Sprintf("Couldn't find %sattributes in the log", overAllErrStr))
-					}
+/*
+Forms the CloudEvent of a single log record without modifying it, the action tells what's done with the record
+once the whole batch is prepared. Keys of the records which aren't duplicates are added to dedupKeys
+An error is returned if on_missing_attributes is `fail` and some mapped fields are missing, subject_template or type_template fails
+or on_invalid_time is `fail` and the time couldn't be parsed
+*/
+func (ce *cloudeventTransformProcessor) prepareLogRecord(ctx context.Context, resource pcommon.Resource, record plog.LogRecord, rec *preparedRecord, dedupKeys *[]string) error {
+	cloudEventData := &rec.data
+	rec.action, rec.duplicate = RECORD_CONVERT, false
+
+	// Get all the required attributes
+	if FETCH_ATTR {
+		missing := ce.mapping.Extract(resource, record, &cloudEventData.Event)
+
+		if len(missing) > 0 {
+			ce.telemetry.recordMissingFields(ctx, missing)
+			if ce.onMissingAttributes != cloudevent.MISSING_ATTR_FAIL {
+				ce.telemetry.recordMissingAttributes(ctx, ce.onMissingAttributes)
+			}
 
-					cloudEventData = cloudeventdata{
-						count:     int(eventCount.Int()),
-						message:   currentMessage.AsString(),
-						name:      eventName.AsString(),
-						namespace: eventNs.AsString(),
-						reason:    reason.AsString(),
-						startTime: startTime.AsString(),
-						uid:       eventUid.AsString(),
-					}
-				} else {
-					cloudEventData = cloudeventdata{
-						count:     0,
-						message:   "",
-						name:      "",
-						namespace: "",
-						reason:    "",
-						startTime: "",
-						uid:       "",
-					}
+			switch ce.onMissingAttributes {
+			case cloudevent.MISSING_ATTR_DROP:
+				rec.action = RECORD_DROP
+				return nil
+			case cloudevent.MISSING_ATTR_PASSTHROUGH:
+				rec.action = RECORD_PASSTHROUGH
+				return nil
+			case cloudevent.MISSING_ATTR_DEFAULT:
+				// Missing fields already have their defaults
+			default:
+				overAllErrStr := ""
+				for _, m := range missing {
+					overAllErrStr += "{" + m + "} "
 				}
 
-				byteData := ce.constructCloudEventJsonBody(&currentMessage, &cloudEventData)
-				byteDataLen := len(byteData)
+				ce.telemetry.recordConversionError(ctx, CONVERSION_ERROR_MISSING_ATTRIBUTES)
+				return errors.New(fmt.Sprintf("Couldn't find %sattributes in the log", overAllErrStr))
+			}
+		}
+	} else {
+		*cloudEventData = cloudeventdata{}
+	}
 
-				_ = currentMessage.SetEmptyBytes()
-				currentMessage.Bytes().EnsureCapacity(byteDataLen)
-				currentMessage.Bytes().Append(byteData...)
+	// Only a record which is converted is remembered, so that a complete one with the same id isn't taken as its duplicate
+	if ce.dedup != nil {
+		if key, ok := ce.dedup.key(resource, record); ok {
+			if !ce.dedup.seen(key) {
+				*dedupKeys = append(*dedupKeys, key)
+			} else {
+				ce.telemetry.recordDuplicate(ctx, ce.dedup.action)
+				if ce.dedup.action == DEDUP_ACTION_DROP {
+					rec.action = RECORD_DROP
+					return nil
+				}
+				rec.duplicate = true
 			}
 		}
 	}
 
+	// Before anything is formed from the data, so that .Data of the templates has the redacted values
+	if ce.redactor != nil {
+		ce.redactor.RedactValues(ctx, cloudEventData.Data)
+		ce.redactor.RedactValues(ctx, cloudEventData.Resource)
+		if len(ce.mapping.AttributesKey) > 0 {
+			cloudEventData.Attributes = ce.redactor.RedactMap(ctx, cloudEventData.Attributes)
+		}
+	}
+
+	var err error
+	if ce.subjectBuilder != nil {
+		if cloudEventData.Subject, err = ce.subjectBuilder.Build(ce.mapping, resource, record, &cloudEventData.Event); err != nil {
+			ce.telemetry.recordConversionError(ctx, CONVERSION_ERROR_TEMPLATE)
+			return err
+		}
+	}
+
+	if cloudEventData.typ, err = ce.typeBuilder.Build(ce.mapping, resource, record, &cloudEventData.Event); err != nil {
+		ce.telemetry.recordConversionError(ctx, CONVERSION_ERROR_TEMPLATE)
+		return err
+	}
+
+	if cloudEventData.time, err = ce.timeResolver.Resolve(resource, record); err != nil {
+		ce.telemetry.recordConversionError(ctx, CONVERSION_ERROR_TIME)
+		return err
+	}
+
+	cloudEventData.extensions = cloudevent.AppendExtensionValues(cloudEventData.extensions[:0], ce.extensions, resource, record)
+	cloudEventData.traceparent = cloudevent.TraceParent(record)
+
+	cloudEventData.partitionKey = ""
+	if ce.partitionKeyBuilder != nil {
+		if cloudEventData.partitionKey, err = ce.partitionKeyBuilder.build(ce.mapping, resource, record, cloudEventData); err != nil {
+			ce.telemetry.recordConversionError(ctx, CONVERSION_ERROR_TEMPLATE)
+			return err
+		}
+	}
+
+	// Templates and extensions read the log as it is (ex: .Attr), so what they've formed is redacted too
+	if ce.redactor != nil {
+		ce.redactor.RedactStrings(ctx, &cloudEventData.ID, &cloudEventData.Subject, &cloudEventData.typ, &cloudEventData.partitionKey)
+		for i := range cloudEventData.extensions {
+			ce.redactor.RedactStrings(ctx, &cloudEventData.extensions[i])
+		}
+	}
+
 	return nil
 }
 
 /*
-This function takes key and adds quotes around it and leaves value as is
-Ex: `key` will become `"key"` and `val` will becom `"val"`
-if the values has quotes in it like `"this value"` it'll become `\"this value\"`
-So if key is `key` and value is `this is my "phone"`
-the return bytearray will look like this `"key":"this is my \"phone\"`
+Converts a single prepared log record to CloudEvent in place, returns true if the record has to be dropped
+In batch mode the event is added to the batch instead, and the record is dropped if it's part of another one
 */
-func appendJsonObjStr(key []byte, val []byte, retSlice []byte) []byte {
+func (ce *cloudeventTransformProcessor) convertLogRecord(ctx context.Context, record plog.LogRecord, rec *preparedRecord, batch *eventBatch) bool {
+	switch rec.action {
+	case RECORD_DROP:
+		return true
+	case RECORD_PASSTHROUGH:
+		return false
+	}
+
+	if rec.duplicate {
+		record.Attributes().PutBool(ATTR_DUPLICATE, true)
+	}
 
-	retSlice = append(retSlice, QUOTE_BYTE)
-	retSlice = append(retSlice, key...)
-	retSlice = append(retSlice, QUOTE_BYTE)
+	cloudEventData := &rec.data
+	currentMessage := record.Body()
 
-	retSlice = append(retSlice, COLON_BYTE)
+	buf := getEncodeBuffer()
+	// Data is constructed first in binary mode as the values may refer to the attributes which are modified here
+	byteData := ce.encodeEvent(*buf, cloudEventData)
+	if ce.mode == MODE_BINARY {
+		ce.putBinaryAttributes(record.Attributes(), cloudEventData)
+	}
+	defer putEncodeBuffer(buf, byteData)
+	ce.telemetry.recordConverted(ctx, ce.mode, len(byteData))
 
-	retSlice = append(retSlice, QUOTE_BYTE)
-	valLen := len(val)
-	for i := 0; i < valLen; i++ {
-		if val[i] != QUOTE_BYTE {
-			retSlice = append(retSlice, val[i])
-		} else {
-			retSlice = append(retSlice, BACKSLASH_BYTE)
-			retSlice = append(retSlice, val[i])
-		}
+	// Set after the data is constructed so that it doesn't show up in the included attributes
+	if len(cloudEventData.partitionKey) > 0 && len(ce.partitionKeyBuilder.attribute) > 0 {
+		record.Attributes().PutStr(ce.partitionKeyBuilder.attribute, cloudEventData.partitionKey)
 	}
-	retSlice = append(retSlice, QUOTE_BYTE)
 
-	return retSlice
+	if batch != nil {
+		return batch.add(record, byteData)
+	}
+
+	setBytesBody(currentMessage, byteData)
+
+	// JSON is told apart by the consumers from the body itself, protobuf isn't
+	if ce.format == cloudevent.FORMAT_PROTOBUF {
+		record.Attributes().PutStr(ATTR_CONTENT_TYPE, cloudevent.CONTENT_TYPE_PROTOBUF)
+	}
+
+	return false
+}
+
+// Encodes the event as the body of the mode, only the data in binary mode
+func (ce *cloudeventTransformProcessor) encodeEvent(retSlice []byte, ev *cloudeventdata) []byte {
+	switch {
+	case ce.mode == MODE_BINARY:
+		return ce.constructCloudEventDataBody(retSlice, ev)
+	case ce.format == cloudevent.FORMAT_PROTOBUF:
+		return ce.constructCloudEventProtoBody(retSlice, ev)
+	}
+	return ce.constructCloudEventJsonBody(retSlice, ev)
 }
 
 /*
-This function takes key and adds quotes around it and leaves value as is
-Ex: `key` will become `"key"` and `val` will remain `val`
-The return output will be `"key":val` which gets appended and returned in retSlice
+This function constructs a Cloudevent message that can take multiple things from the passed config and the message that receiver sents
+At the end it'll form a JSON where every string is escaped as per RFC 8259 just to construct a good byte array that's readable
+by kafka and is easily parseable. It's appended to retSlice (a pooled scratch buffer) and the parts which are the same for
+every event come already escaped from the encoder, so nothing is allocated once the buffer is big enough
 */
-func appendJsonObjElse(key []byte, val []byte, retSlice []byte) []byte {
-	retSlice = append(retSlice, QUOTE_BYTE)
-	retSlice = append(retSlice, key...)
-	retSlice = append(retSlice, QUOTE_BYTE)
+func (ce *cloudeventTransformProcessor) constructCloudEventJsonBody(retSlice []byte, msgData *cloudeventdata) []byte {
+	//{"datacontenttype":"application/json; charset=utf-8","id":"%s","source":"%s","specversion":"%s","type":"%s","data":%s}
+	enc := ce.encoder
 
-	retSlice = append(retSlice, COLON_BYTE)
+	// data body
+	bytesData := ce.isBytesData(msgData)
+
+	if bytesData {
+		retSlice = append(retSlice, enc.headBytes...)
+	} else {
+		retSlice = append(retSlice, enc.headJson...)
+	}
+	retSlice = cloudevent.AppendJsonStr(msgData.ID, retSlice)
+	retSlice = append(retSlice, enc.sourceType...)
+	retSlice = cloudevent.AppendJsonStr(msgData.typ, retSlice)
+	if len(msgData.Subject) > 0 {
+		retSlice = cloudevent.AppendJsonStr(msgData.Subject, append(retSlice, fragmentSubject...))
+	}
+	if len(msgData.time) > 0 {
+		retSlice = cloudevent.AppendJsonStr(msgData.time, append(retSlice, fragmentTime...))
+	}
+	retSlice = append(retSlice, enc.dataSchema...)
+	for i, val := range msgData.extensions {
+		if len(val) > 0 {
+			retSlice = cloudevent.AppendJsonStr(val, append(retSlice, enc.extensionKeys[i]...))
+		}
+	}
+	if len(msgData.traceparent) > 0 {
+		retSlice = cloudevent.AppendJsonStr(msgData.traceparent, append(retSlice, fragmentTraceparent...))
+	}
+	if len(msgData.partitionKey) > 0 {
+		retSlice = cloudevent.AppendJsonStr(msgData.partitionKey, append(retSlice, fragmentPartitionKey...))
+	}
 
-	retSlice = append(retSlice, val...)
+	if bytesData {
+		// JSON can't carry the bytes as is, they're base64 encoded under the attribute of the spec version
+		retSlice = appendJsonBase64(msgData.Data[0].Bytes().AsRaw(), append(retSlice, enc.dataBase64...))
+	} else {
+		retSlice = ce.constructCloudEventDataBody(append(retSlice, fragmentData...), msgData)
+	}
 
+	retSlice = append(retSlice, cloudevent.CLOSE_BRACE_BYTE)
 	return retSlice
 }
 
 /*
-Function takes pretext from the params passed in config append_type and adds the reason to it
-Ex: append_type: `com.company.event` and reason: `Created Successfully`
-function will return `com.company.event.CreatedSuccessfully`
+Appends the CloudEvent in the protobuf format of the spec (CloudEvent message of cloudevents.proto). Attributes other
+than id, source, specversion and type go in the attributes map, with the same names as in JSON, and the data is
+text_data which has the JSON of data (binary_data for bytes from mapping.data_from)
 */
-func configureCeType(pretext string, reason string) string {
-	var ret strings.Builder
-	ret.Grow(len(pretext) + len(reason))
-
-	ret.WriteString(pretext)
-	ret.WriteRune('.')
-	ret.WriteString(typeVersion) // It'll define the version
-	ret.WriteRune('.')
-
-	for _, ch := range reason {
-		if !unicode.IsSpace(ch) {
-			ret.WriteRune(ch)
+func (ce *cloudeventTransformProcessor) constructCloudEventProtoBody(retSlice []byte, msgData *cloudeventdata) []byte {
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_ID, msgData.ID)
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_SOURCE, ce.source)
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_SPEC_VERSION, ce.spec.Version)
+	retSlice = cloudevent.AppendProtoString(retSlice, cloudevent.PROTO_FIELD_TYPE, msgData.typ)
+
+	bytesData := ce.isBytesData(msgData)
+
+	if bytesData {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, "datacontenttype", cloudevent.PROTO_FIELD_CE_STRING, CONTENT_TYPE_OCTET_STREAM)
+	} else {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, "datacontenttype", cloudevent.PROTO_FIELD_CE_STRING, CONTENT_TYPE_JSON)
+	}
+	if len(msgData.Subject) > 0 {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, "subject", cloudevent.PROTO_FIELD_CE_STRING, msgData.Subject)
+	}
+	if len(msgData.time) > 0 {
+		retSlice = cloudevent.AppendProtoTimeAttribute(retSlice, "time", msgData.time)
+	}
+	if len(ce.dataSchema) > 0 {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, ce.spec.DataSchemaAttr, cloudevent.PROTO_FIELD_CE_URI, ce.dataSchema)
+	}
+	for i, val := range msgData.extensions {
+		if len(val) > 0 {
+			retSlice = cloudevent.AppendProtoAttribute(retSlice, ce.extensions[i].Name, cloudevent.PROTO_FIELD_CE_STRING, val)
 		}
 	}
+	if len(msgData.traceparent) > 0 {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, cloudevent.EXTENSION_TRACEPARENT, cloudevent.PROTO_FIELD_CE_STRING, msgData.traceparent)
+	}
+	if len(msgData.partitionKey) > 0 {
+		retSlice = cloudevent.AppendProtoAttribute(retSlice, EXTENSION_PARTITION_KEY, cloudevent.PROTO_FIELD_CE_STRING, msgData.partitionKey)
+	}
+
+	if bytesData {
+		retSlice = protowire.AppendTag(retSlice, cloudevent.PROTO_FIELD_BINARY_DATA, protowire.BytesType)
+		return protowire.AppendBytes(retSlice, msgData.Data[0].Bytes().AsRaw())
+	}
 
-	return ret.String()
+	// Size of the data isn't known till it's encoded
+	retSlice = protowire.AppendTag(retSlice, cloudevent.PROTO_FIELD_TEXT_DATA, protowire.BytesType)
+	start := len(retSlice)
+	retSlice = cloudevent.ValidUTF8After(ce.constructCloudEventDataBody(retSlice, msgData), start)
+	return cloudevent.InsertProtoLength(retSlice, start)
 }
 
 /*
-This function constructs a Cloudevent message that can take multiple things from the passed config and the message that receiver sents
-At the end it'll form a JSON which escapes any quotes that's found in the string just to construct a good byte array that's readable
-by kafka and is easily parseable
+Appends the JSON object that goes in the `data` of CloudEvent to retSlice, in structured mode it's nested in
+the envelope and in binary mode it's the whole body of the log. Keys are the ones configured in mapping.data,
+with mapping.data_from the value of that field is the data (bytes are appended as they are)
 */
-func (ce *cloudeventTransformProcessor) constructCloudEventJsonBody(vals *pcommon.Value, msgData *cloudeventdata) []byte {
-	//{"datacontenttype":"application/json; charset=utf-8","id":"%s","source":"%s","specversion":"%s","type":"%s","data":%s}
-	retSlice := make([]byte, 0, 512)
+func (ce *cloudeventTransformProcessor) constructCloudEventDataBody(retSlice []byte, msgData *cloudeventdata) []byte {
+	if field := ce.mapping.DataFrom; field != nil {
+		val := msgData.Data[0]
+		switch {
+		case val.Type() == pcommon.ValueTypeBytes:
+			return append(retSlice, val.Bytes().AsRaw()...)
+		case field.JSON && val.Type() == pcommon.ValueTypeStr:
+			return cloudevent.AppendJsonEmbedded(val.Str(), retSlice)
+		}
+		return cloudevent.AppendJsonValue(val, retSlice)
+	}
+
+	//{"reason":"%s","start_time":"%s","name":"%s","namespace":"%s","count":%s,"message":"%s"}
+	retSlice = append(retSlice, cloudevent.OPEN_BRACE_BYTE)
+	for i, val := range msgData.Data {
+		if i > 0 {
+			retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+		}
+		retSlice = append(retSlice, ce.encoder.dataKeys[i]...)
+
+		if ce.mapping.Data[i].JSON && val.Type() == pcommon.ValueTypeStr {
+			retSlice = cloudevent.AppendJsonEmbedded(val.Str(), retSlice)
+		} else {
+			retSlice = cloudevent.AppendJsonValue(val, retSlice)
+		}
+	}
+	if len(ce.mapping.AttributesKey) > 0 {
+		if len(msgData.Data) > 0 {
+			retSlice = append(retSlice, cloudevent.COMMA_BYTE)
+		}
+		retSlice = cloudevent.AppendJsonMap(msgData.Attributes, append(retSlice, ce.encoder.attributesKey...))
+	}
+	hasKeys := len(msgData.Data) > 0 || len(ce.mapping.AttributesKey) > 0
+	retSlice = cloudevent.AppendResourceJson(retSlice, ce.mapping.Resource, msgData.Resource, hasKeys)
+	retSlice = append(retSlice, cloudevent.CLOSE_BRACE_BYTE)
 
-	// data body
-	retSlice = append(retSlice, OPEN_BRACE_BYTE)
-	retSlice = appendJsonObjStr([]byte("datacontenttype"), []byte("application/json; charset=utf-8"), retSlice)
-	retSlice = append(retSlice, COMMA_BYTE)
-	retSlice = appendJsonObjStr([]byte("id"), []byte(msgData.uid), retSlice)
-	retSlice = append(retSlice, COMMA_BYTE)
-	retSlice = appendJsonObjStr([]byte("source"), []byte(ce.source), retSlice)
-	retSlice = append(retSlice, COMMA_BYTE)
-	retSlice = appendJsonObjStr([]byte("specversion"), []byte(ce.specversion), retSlice)
-	retSlice = append(retSlice, COMMA_BYTE)
-	retSlice = appendJsonObjStr([]byte("type"), []byte(configureCeType(ce.typ, msgData.reason)), retSlice)
-	retSlice = append(retSlice, COMMA_BYTE)
-	retSlice = append(retSlice, QUOTE_BYTE)
-	retSlice = append(retSlice, []byte("data")...)
-	retSlice = append(retSlice, QUOTE_BYTE)
-	retSlice = append(retSlice, COLON_BYTE)
-
-	{
-		//{"reason":"%s","start_time":"%s","name":"%s","uid":"%s","namespace":"%s","count":%s,"message":"%s"}
-		retSlice = append(retSlice, OPEN_BRACE_BYTE)
-		retSlice = appendJsonObjStr([]byte("reason"), []byte(msgData.reason), retSlice)
-		retSlice = append(retSlice, COMMA_BYTE)
-		retSlice = appendJsonObjStr([]byte("start_time"), []byte(msgData.startTime), retSlice)
-		retSlice = append(retSlice, COMMA_BYTE)
-		retSlice = appendJsonObjStr([]byte("name"), []byte(msgData.name), retSlice)
-		retSlice = append(retSlice, COMMA_BYTE)
-		//retSlice = appendJsonObjStr([]byte("uid"), []byte(msgData.uid), retSlice)
-		//retSlice = append(retSlice, COMMA_BYTE)
-		retSlice = appendJsonObjStr([]byte("namespace"), []byte(msgData.namespace), retSlice)
-		retSlice = append(retSlice, COMMA_BYTE)
-		retSlice = appendJsonObjElse([]byte("count"), []byte(strconv.Itoa(msgData.count)), retSlice)
-		retSlice = append(retSlice, COMMA_BYTE)
-		retSlice = appendJsonObjStr([]byte("message"), []byte(msgData.message), retSlice)
-		retSlice = append(retSlice, CLOSE_BRACE_BYTE)
-	}
-
-	retSlice = append(retSlice, CLOSE_BRACE_BYTE)
 	return retSlice
 }
+
+/*
+In binary mode the context attributes are put in the log attributes with ATTR_CE_PREFIX
+so exporters (like kafka) can map them directly to the transport headers
+*/
+func (ce *cloudeventTransformProcessor) putBinaryAttributes(attrs pcommon.Map, msgData *cloudeventdata) {
+	attrs.PutStr(ATTR_CE_ID, msgData.ID)
+	attrs.PutStr(ATTR_CE_SOURCE, ce.source)
+	attrs.PutStr(ATTR_CE_SPECVERSION, ce.spec.Version)
+	attrs.PutStr(ATTR_CE_TYPE, msgData.typ)
+	if len(msgData.Subject) > 0 {
+		attrs.PutStr(ATTR_CE_SUBJECT, msgData.Subject)
+	}
+	if len(msgData.time) > 0 {
+		attrs.PutStr(ATTR_CE_TIME, msgData.time)
+	}
+	if len(ce.dataSchema) > 0 {
+		attrs.PutStr(ATTR_CE_PREFIX+ce.spec.DataSchemaAttr, ce.dataSchema)
+	}
+	for i, val := range msgData.extensions {
+		if len(val) > 0 {
+			attrs.PutStr(ATTR_CE_PREFIX+ce.extensions[i].Name, val)
+		}
+	}
+	if len(msgData.traceparent) > 0 {
+		attrs.PutStr(ATTR_CE_PREFIX+cloudevent.EXTENSION_TRACEPARENT, msgData.traceparent)
+	}
+	if len(msgData.partitionKey) > 0 {
+		attrs.PutStr(ATTR_CE_PREFIX+EXTENSION_PARTITION_KEY, msgData.partitionKey)
+	}
+	if ce.isBytesData(msgData) {
+		attrs.PutStr(ATTR_CONTENT_TYPE, CONTENT_TYPE_OCTET_STREAM)
+	} else {
+		attrs.PutStr(ATTR_CONTENT_TYPE, CONTENT_TYPE_JSON)
+	}
+}
+
+// Bytes taken from mapping.data_from aren't JSON, they're sent as they are (base64 encoded in structured mode)
+func (ce *cloudeventTransformProcessor) isBytesData(msgData *cloudeventdata) bool {
+	return ce.mapping.DataFrom != nil && msgData.Data[0].Type() == pcommon.ValueTypeBytes
+}
@@ -0,0 +1,185 @@
+package cloudeventtransform
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/instrument"
+)
+
+const (
+	// Name of the meter and the prefix of the metrics this processor reports
+	METER_NAME    = "github.com/hv/akash.chandra/cloudeventtransform"
+	METRIC_PREFIX = "processor_" + typeStr + "_"
+
+	METRIC_ATTR_POLICY = "policy"
+	METRIC_ATTR_ACTION = "action"
+	METRIC_ATTR_RULE   = "rule"
+	METRIC_ATTR_REASON = "reason"
+	METRIC_ATTR_MODE   = "mode"
+	METRIC_ATTR_ERROR  = "error"
+	METRIC_ATTR_FIELD  = "field" // configured name of a mapped field, not its value
+
+	// Why a log was filtered out
+	FILTER_REASON_EVENT_REASON = "event_reason" // k8s.event.reason isn't in filter
+	FILTER_REASON_RULES        = "rules"        // filters rules didn't let it pass
+
+	// What failed the conversion of a log
+	CONVERSION_ERROR_MISSING_ATTRIBUTES = "missing_attributes" // with on_missing_attributes fail
+	CONVERSION_ERROR_TEMPLATE           = "template"           // type, subject or partition key template
+	CONVERSION_ERROR_TIME               = "time"               // with on_invalid_time fail
+)
+
+// Metrics reported by the processor, attribute values are always from a fixed set to keep cardinality bounded
+type processorTelemetry struct {
+	recordsIn         instrument.Int64Counter
+	recordsFiltered   instrument.Int64Counter
+	recordsConverted  instrument.Int64Counter
+	conversionErrors  instrument.Int64Counter
+	missingFields     instrument.Int64Counter
+	envelopeSize      instrument.Int64Histogram
+	missingAttributes instrument.Int64Counter
+	duplicates        instrument.Int64Counter
+	decodeErrors      instrument.Int64Counter
+	redactions        instrument.Int64Counter
+}
+
+func newProcessorTelemetry(set component.TelemetrySettings) (*processorTelemetry, error) {
+	meter := set.MeterProvider.Meter(METER_NAME)
+
+	recordsIn, err := meter.Int64Counter(
+		METRIC_PREFIX+"records_in",
+		instrument.WithDescription("Number of log records received by the processor"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	recordsFiltered, err := meter.Int64Counter(
+		METRIC_PREFIX+"records_filtered",
+		instrument.WithDescription("Number of log records removed by filter or filters, by reason"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	recordsConverted, err := meter.Int64Counter(
+		METRIC_PREFIX+"records_converted",
+		instrument.WithDescription("Number of log records converted to CloudEvents, by mode"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	conversionErrors, err := meter.Int64Counter(
+		METRIC_PREFIX+"conversion_errors",
+		instrument.WithDescription("Number of log records whose conversion failed, by error"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	missingFields, err := meter.Int64Counter(
+		METRIC_PREFIX+"missing_fields",
+		instrument.WithDescription("Number of times a mapped field was missing in a log record, by field"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeSize, err := meter.Int64Histogram(
+		METRIC_PREFIX+"envelope_size",
+		instrument.WithDescription("Size of the encoded CloudEvent (data in binary mode), by mode"),
+		instrument.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	missingAttributes, err := meter.Int64Counter(
+		METRIC_PREFIX+"records_missing_attributes",
+		instrument.WithDescription("Number of log records which didn't have all the mapped fields, by on_missing_attributes policy"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates, err := meter.Int64Counter(
+		METRIC_PREFIX+"records_duplicate",
+		instrument.WithDescription("Number of log records which were already seen, by dedup action"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	decodeErrors, err := meter.Int64Counter(
+		METRIC_PREFIX+"records_decode_failed",
+		instrument.WithDescription("Number of log records whose body wasn't a valid CloudEvent, by on_decode_error policy"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	redactions, err := meter.Int64Counter(
+		METRIC_PREFIX+"redactions",
+		instrument.WithDescription("Number of matches redacted in the event data, by redaction detector or rule name"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &processorTelemetry{
+		recordsIn:         recordsIn,
+		recordsFiltered:   recordsFiltered,
+		recordsConverted:  recordsConverted,
+		conversionErrors:  conversionErrors,
+		missingFields:     missingFields,
+		envelopeSize:      envelopeSize,
+		missingAttributes: missingAttributes,
+		duplicates:        duplicates,
+		decodeErrors:      decodeErrors,
+		redactions:        redactions,
+	}, nil
+}
+
+func (t *processorTelemetry) recordIn(ctx context.Context, count int) {
+	t.recordsIn.Add(ctx, int64(count))
+}
+
+func (t *processorTelemetry) recordFiltered(ctx context.Context, reason string) {
+	t.recordsFiltered.Add(ctx, 1, attribute.String(METRIC_ATTR_REASON, reason))
+}
+
+func (t *processorTelemetry) recordConverted(ctx context.Context, mode string, size int) {
+	attr := attribute.String(METRIC_ATTR_MODE, mode)
+	t.recordsConverted.Add(ctx, 1, attr)
+	t.envelopeSize.Record(ctx, int64(size), attr)
+}
+
+func (t *processorTelemetry) recordConversionError(ctx context.Context, kind string) {
+	t.conversionErrors.Add(ctx, 1, attribute.String(METRIC_ATTR_ERROR, kind))
+}
+
+// fields are the configured names of the mapped fields, so there are only as many as in the configuration
+func (t *processorTelemetry) recordMissingFields(ctx context.Context, fields []string) {
+	for _, f := range fields {
+		t.missingFields.Add(ctx, 1, attribute.String(METRIC_ATTR_FIELD, f))
+	}
+}
+
+func (t *processorTelemetry) recordMissingAttributes(ctx context.Context, policy string) {
+	t.missingAttributes.Add(ctx, 1, attribute.String(METRIC_ATTR_POLICY, policy))
+}
+
+func (t *processorTelemetry) recordDuplicate(ctx context.Context, action string) {
+	t.duplicates.Add(ctx, 1, attribute.String(METRIC_ATTR_ACTION, action))
+}
+
+func (t *processorTelemetry) recordDecodeError(ctx context.Context, policy string) {
+	t.decodeErrors.Add(ctx, 1, attribute.String(METRIC_ATTR_POLICY, policy))
+}
+
+func (t *processorTelemetry) recordRedactions(ctx context.Context, rule string, count int64) {
+	t.redactions.Add(ctx, count, attribute.String(METRIC_ATTR_RULE, rule))
+}
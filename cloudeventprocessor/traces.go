@@ -0,0 +1,156 @@
+package cloudeventtransform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// Keys of the log body formed from a span, mapping and filters read them as `body.<key>`
+	SPAN_FIELD_NAME           = "name"
+	SPAN_FIELD_KIND           = "kind"
+	SPAN_FIELD_STATUS_CODE    = "status_code" // Unset, Ok or Error
+	SPAN_FIELD_STATUS_MESSAGE = "status_message"
+	SPAN_FIELD_TRACE_ID       = "trace_id"
+	SPAN_FIELD_SPAN_ID        = "span_id"
+	SPAN_FIELD_PARENT_SPAN_ID = "parent_span_id"
+	SPAN_FIELD_START_TIME     = "start_time"
+	SPAN_FIELD_END_TIME       = "end_time"
+	SPAN_FIELD_DURATION_MS    = "duration_ms"
+	SPAN_FIELD_EVENT          = "event" // `span.` and the lowercase status code, ex: `span.error`
+)
+
+/*
+TracesConfig selects the spans which are converted to CloudEvents when the processor is in a traces pipeline
+Rules are the same as `filters` and run on the log formed from the span, failed spans are selected when
+there aren't any include rules. Spans themselves are passed on as they are
+*/
+type TracesConfig struct {
+	cloudevent.FiltersConfig `mapstructure:",squash"`
+	Mapping                  cloudevent.MappingConfig `mapstructure:"mapping"`
+}
+
+// spanConverter forms a log from each span and sends the selected ones as CloudEvents
+type spanConverter struct {
+	*eventSender
+}
+
+/*
+Data keys of the span CloudEvents when nothing is configured in traces.mapping, attributes of the span
+are added under `attributes`
+*/
+func defaultSpanDataMapping() []cloudevent.DataFieldMapping {
+	return []cloudevent.DataFieldMapping{
+		{Key: SPAN_FIELD_NAME, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_NAME},
+		{Key: SPAN_FIELD_KIND, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_KIND},
+		{Key: SPAN_FIELD_STATUS_CODE, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_STATUS_CODE},
+		{Key: SPAN_FIELD_STATUS_MESSAGE, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_STATUS_MESSAGE},
+		{Key: SPAN_FIELD_TRACE_ID, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_TRACE_ID},
+		{Key: SPAN_FIELD_SPAN_ID, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_SPAN_ID},
+		{Key: SPAN_FIELD_PARENT_SPAN_ID, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_PARENT_SPAN_ID},
+		{Key: SPAN_FIELD_START_TIME, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_START_TIME},
+		{Key: SPAN_FIELD_END_TIME, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_END_TIME},
+		{Key: SPAN_FIELD_DURATION_MS, From: cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_DURATION_MS},
+	}
+}
+
+// Validate checks the rules and the mapping, mapping is checked with the default data keys if there aren't any
+func (cfg *TracesConfig) Validate() error {
+	if err := cfg.FiltersConfig.Validate(); err != nil {
+		return fmt.Errorf("traces: %w", err)
+	}
+
+	mappingCfg := cfg.Mapping
+	if len(mappingCfg.Data) == 0 {
+		mappingCfg.Data = defaultSpanDataMapping()
+	}
+
+	if err := mappingCfg.Validate(); err != nil {
+		return fmt.Errorf("traces: %w", err)
+	}
+
+	return nil
+}
+
+func newSpanConverter(set component.TelemetrySettings, cfg *Config) (*spanConverter, error) {
+	filters := cfg.Traces.FiltersConfig
+	if len(filters.Include) == 0 {
+		filters.Include = []cloudevent.FilterRule{{
+			Field:  cloudevent.FIELD_PREFIX_BODY + SPAN_FIELD_STATUS_CODE,
+			Values: []string{ptrace.StatusCodeError.String()},
+		}}
+	}
+
+	sender, err := newEventSender(set, cfg, cfg.eventsConfig(filters, cfg.Traces.Mapping, defaultSpanDataMapping()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &spanConverter{eventSender: sender}, nil
+}
+
+// Converts the selected spans and sends them to the output exporters, spans are passed on as they are
+func (c *spanConverter) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	_ = c.send(ctx, spansToLogs(td), "spans")
+	return td, nil
+}
+
+/*
+Forms a log from every span, the span fields are in a map body (see SPAN_FIELD_*) and the attributes,
+resource and scope are the same as the span. Timestamp of the log is the end of the span
+*/
+func spansToLogs(td ptrace.Traces) plog.Logs {
+	ld := plog.NewLogs()
+	observed := pcommon.NewTimestampFromTime(time.Now())
+
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		rl := ld.ResourceLogs().AppendEmpty()
+		rs.Resource().CopyTo(rl.Resource())
+
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			sl := rl.ScopeLogs().AppendEmpty()
+			ss.Scope().CopyTo(sl.Scope())
+
+			for k := 0; k < ss.Spans().Len(); k++ {
+				spanToLog(ss.Spans().At(k), sl.LogRecords().AppendEmpty(), observed)
+			}
+		}
+	}
+
+	return ld
+}
+
+func spanToLog(span ptrace.Span, lr plog.LogRecord, observed pcommon.Timestamp) {
+	lr.SetTimestamp(span.EndTimestamp())
+	lr.SetObservedTimestamp(observed)
+	lr.SetTraceID(span.TraceID())
+	lr.SetSpanID(span.SpanID())
+	lr.SetFlags(plog.DefaultLogRecordFlags.WithIsSampled(true)) // spans which reached the collector were recorded
+	span.Attributes().CopyTo(lr.Attributes())
+
+	status := span.Status().Code().String()
+
+	body := lr.Body().SetEmptyMap()
+	body.EnsureCapacity(11)
+	body.PutStr(SPAN_FIELD_NAME, span.Name())
+	body.PutStr(SPAN_FIELD_KIND, span.Kind().String())
+	body.PutStr(SPAN_FIELD_STATUS_CODE, status)
+	body.PutStr(SPAN_FIELD_STATUS_MESSAGE, span.Status().Message())
+	body.PutStr(SPAN_FIELD_TRACE_ID, span.TraceID().String())
+	body.PutStr(SPAN_FIELD_SPAN_ID, span.SpanID().String())
+	body.PutStr(SPAN_FIELD_PARENT_SPAN_ID, span.ParentSpanID().String())
+	body.PutStr(SPAN_FIELD_START_TIME, span.StartTimestamp().AsTime().UTC().Format(time.RFC3339Nano))
+	body.PutStr(SPAN_FIELD_END_TIME, span.EndTimestamp().AsTime().UTC().Format(time.RFC3339Nano))
+	body.PutDouble(SPAN_FIELD_DURATION_MS, float64(span.EndTimestamp()-span.StartTimestamp())/float64(time.Millisecond))
+	body.PutStr(SPAN_FIELD_EVENT, "span."+strings.ToLower(status))
+}
@@ -0,0 +1,115 @@
+package cloudeventtransform
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudevent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+var testStart = time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func appendTestSpan(spans ptrace.SpanSlice, name string, code ptrace.StatusCode, spanID byte) {
+	span := spans.AppendEmpty()
+	span.SetName(name)
+	span.SetKind(ptrace.SpanKindInternal)
+	span.SetTraceID(pcommon.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36})
+	span.SetSpanID(pcommon.SpanID{0, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7 + spanID})
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(testStart))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(testStart.Add(1500 * time.Millisecond)))
+	span.Status().SetCode(code)
+	span.Status().SetMessage("rollout timed out")
+	span.Attributes().PutStr("deployment", "checkout")
+}
+
+func testTraces() ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "deployer")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	appendTestSpan(spans, "deploy checkout", ptrace.StatusCodeError, 0)
+	appendTestSpan(spans, "deploy cart", ptrace.StatusCodeOk, 1)
+	appendTestSpan(spans, "build checkout", ptrace.StatusCodeError, 2)
+	return td
+}
+
+func startTestTracesProcessor(t *testing.T, cfg *Config) (*consumertest.TracesSink, *consumertest.LogsSink) {
+	events := component.NewIDWithName("kafka", "events")
+	cfg.Output.Exporters = []component.ID{events}
+	host, sinks := newTestHost(events)
+
+	next := new(consumertest.TracesSink)
+	p, err := NewFactory().CreateTracesProcessor(context.Background(), processortest.NewNopCreateSettings(), cfg, next)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), host))
+	t.Cleanup(func() { require.NoError(t, p.Shutdown(context.Background())) })
+
+	require.NoError(t, p.ConsumeTraces(context.Background(), testTraces()))
+	return next, sinks[events].LogsSink
+}
+
+func TestSpansToCloudEvents(t *testing.T) {
+	cfg := testConfig()
+	cfg.Traces.Include = []cloudevent.FilterRule{{Field: "body.name", Match: cloudevent.MATCH_PREFIX, Values: []string{"deploy"}}}
+	cfg.Traces.Exclude = []cloudevent.FilterRule{{Field: "body.status_code", Values: []string{"Ok"}}}
+	next, events := startTestTracesProcessor(t, cfg)
+
+	// Spans are passed on as they are
+	assert.Equal(t, 3, next.SpanCount())
+
+	require.Equal(t, 1, events.LogRecordCount())
+	event := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(events.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Bytes().AsRaw(), &event))
+
+	assert.Equal(t, "00f067aa0ba902b7", event["id"])
+	assert.Equal(t, "com.company.event.v1.span.error", event["type"])
+	assert.Equal(t, "2023-03-01T10:00:01.5Z", event["time"])
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", event[cloudevent.EXTENSION_TRACEPARENT])
+	assert.Equal(t, map[string]interface{}{
+		"name":           "deploy checkout",
+		"kind":           "Internal",
+		"status_code":    "Error",
+		"status_message": "rollout timed out",
+		"trace_id":       "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":        "00f067aa0ba902b7",
+		"parent_span_id": "",
+		"start_time":     "2023-03-01T10:00:00Z",
+		"end_time":       "2023-03-01T10:00:01.5Z",
+		"duration_ms":    1500.0,
+		"attributes":     map[string]interface{}{"deployment": "checkout"},
+	}, event["data"])
+}
+
+func TestSpansToCloudEventsDefaults(t *testing.T) {
+	cfg := testConfig()
+	cfg.Traces.Mapping.Data = []cloudevent.DataFieldMapping{{Key: "service", From: "resource.service.name"}}
+	_, events := startTestTracesProcessor(t, cfg)
+
+	// Only the failed spans are converted when there aren't any rules
+	require.Equal(t, 2, events.LogRecordCount())
+	records := events.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	for i := 0; i < records.Len(); i++ {
+		assert.Equal(t, map[string]interface{}{
+			"service":    "deployer",
+			"attributes": map[string]interface{}{"deployment": "checkout"},
+		}, jsonBodyData(t, records.At(i)))
+	}
+}
+
+func TestTracesProcessorNeedsOutput(t *testing.T) {
+	_, err := NewFactory().CreateTracesProcessor(context.Background(), processortest.NewNopCreateSettings(), testConfig(), new(consumertest.TracesSink))
+	assert.Error(t, err)
+
+	cfg := testConfig()
+	cfg.Traces.Mapping.IncludeAttributes = SPAN_FIELD_NAME
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,41 @@
+/*
+Package cloudevent forms CloudEvents out of log records, it's shared by the processor and the exporter which
+only differ in how the events are written out. Mapping, templates, time, ids, filters, redaction and the JSON
+and protobuf encoding of the values are here
+*/
+package cloudevent
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	// Attributes of the logs that k8seventsreceiver gives
+	ATTR_EVENT_COUNT      = "k8s.event.count"
+	ATTR_EVENT_NAME       = "k8s.event.name"
+	ATTR_EVENT_NS         = "k8s.namespace.name"
+	ATTR_EVENT_REASON     = "k8s.event.reason"
+	ATTR_EVENT_START_TIME = "k8s.event.start_time"
+	ATTR_EVENT_UID        = "k8s.event.uid"
+	ATTR_OBJECT_KIND      = "k8s.object.kind"
+	ATTR_OBJECT_NAME      = "k8s.object.name"
+
+	BACKSLASH_BYTE     = byte('\\')
+	CLOSE_BRACE_BYTE   = byte('}')
+	CLOSE_BRACKET_BYTE = byte(']')
+	COLON_BYTE         = byte(':')
+	COMMA_BYTE         = byte(',')
+	OPEN_BRACE_BYTE    = byte('{')
+	OPEN_BRACKET_BYTE  = byte('[')
+	QUOTE_BYTE         = byte('"')
+)
+
+// Event has the fields of a log record which the mapping fills, the templates are executed on them
+type Event struct {
+	ID         string
+	Subject    string
+	TypeSuffix string          // Gets added at the end of the CloudEvent type
+	Data       []pcommon.Value // Values of the keys in mapping.data, in the same order
+	Resource   []pcommon.Value // Values of resource_attributes copied in data, empty ones are left out
+	Attributes pcommon.Map     // Attributes of the log, written in data if mapping.include_attributes is set
+}
@@ -0,0 +1,106 @@
+package cloudevent
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// CloudEvents spec recommends extension names not longer than this, some transports can't carry longer ones
+	MAX_EXTENSION_NAME_LEN = 20
+)
+
+/*
+Attribute names defined by the spec (any version) and by this component, extensions can't use them
+as they would conflict with the context attributes in the envelope or the headers
+*/
+var reservedAttributeNames = map[string]bool{
+	"id":                  true,
+	"source":              true,
+	"specversion":         true,
+	"type":                true,
+	"datacontenttype":     true,
+	"dataschema":          true,
+	"schemaurl":           true,
+	"datacontentencoding": true,
+	"subject":             true,
+	"time":                true,
+	"data":                true,
+	"data_base64":         true,
+
+	EXTENSION_TRACEPARENT: true,
+	EXTENSION_TRACESTATE:  true,
+}
+
+// ExtensionConfig is one CloudEvent extension attribute, either a static value or a field of the log
+type ExtensionConfig struct {
+	Name  string `mapstructure:"name"`
+	Value string `mapstructure:"value"` // static value, used when from is empty
+	From  string `mapstructure:"from"`  // field of the log written the same way as in mapping, left out when missing
+}
+
+type Extension struct {
+	Name  string
+	value string
+	field *FieldRef // nil for static values
+}
+
+// Checks the extensions against the CloudEvents naming rules, names have to be lowercase letters or digits
+func ValidateExtensions(extensions []ExtensionConfig) error {
+	names := make(map[string]bool, len(extensions))
+
+	for _, ext := range extensions {
+		if len(ext.Name) == 0 || len(ext.Name) > MAX_EXTENSION_NAME_LEN {
+			return fmt.Errorf("extension name should have 1 to %d characters, provided: '%s'", MAX_EXTENSION_NAME_LEN, ext.Name)
+		}
+
+		for _, ch := range ext.Name {
+			if (ch < 'a' || ch > 'z') && (ch < '0' || ch > '9') {
+				return fmt.Errorf("extension name can only have lowercase letters and digits, provided: '%s'", ext.Name)
+			}
+		}
+
+		if reservedAttributeNames[ext.Name] {
+			return fmt.Errorf("extension name '%s' is a CloudEvents attribute", ext.Name)
+		}
+
+		if names[ext.Name] {
+			return fmt.Errorf("extension '%s' is provided more than once", ext.Name)
+		}
+		names[ext.Name] = true
+
+		if (len(ext.Value) == 0) == (len(ext.From) == 0) {
+			return fmt.Errorf("extension '%s' needs either value or from", ext.Name)
+		}
+	}
+
+	return nil
+}
+
+func NewExtensions(extensions []ExtensionConfig) []Extension {
+	ret := make([]Extension, 0, len(extensions))
+	for _, ext := range extensions {
+		ret = append(ret, Extension{Name: ext.Name, value: ext.Value, field: newOptionalFieldRef(ext.From)})
+	}
+	return ret
+}
+
+/*
+Appends the values of the extensions to values in the same order, a value is empty when its field
+is missing in the log and the extension is left out then
+*/
+func AppendExtensionValues(values []string, extensions []Extension, res pcommon.Resource, lr plog.LogRecord) []string {
+	for i := range extensions {
+		ext := &extensions[i]
+		if ext.field == nil {
+			values = append(values, ext.value)
+		} else if val, ok := ext.field.Get(res, lr); ok {
+			values = append(values, val.AsString())
+		} else {
+			values = append(values, "")
+		}
+	}
+	return values
+}
@@ -0,0 +1,49 @@
+package cloudevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestValidateExtensions(t *testing.T) {
+	assert.NoError(t, ValidateExtensions(nil))
+	assert.NoError(t, ValidateExtensions([]ExtensionConfig{
+		{Name: "cluster", From: "k8s.cluster.name"},
+		{Name: "env", Value: "prod"},
+		{Name: "tenant2", From: "resource.tenant"},
+	}))
+
+	tests := map[string][]ExtensionConfig{
+		"empty name":     {{Name: "", Value: "a"}},
+		"uppercase":      {{Name: "Cluster", Value: "a"}},
+		"underscore":     {{Name: "k8s_cluster", Value: "a"}},
+		"dot":            {{Name: "k8s.cluster", Value: "a"}},
+		"too long":       {{Name: "abcdefghijklmnopqrstu", Value: "a"}},
+		"reserved":       {{Name: "subject", Value: "a"}},
+		"duplicate":      {{Name: "env", Value: "a"}, {Name: "env", Value: "b"}},
+		"no value":       {{Name: "env"}},
+		"value and from": {{Name: "env", Value: "prod", From: "env"}},
+	}
+	for name, extensions := range tests {
+		assert.Error(t, ValidateExtensions(extensions), name)
+	}
+}
+
+func TestExtensionValues(t *testing.T) {
+	res := pcommon.NewResource()
+	res.Attributes().PutStr("k8s.cluster.name", "east-1")
+	lr := plog.NewLogRecord()
+	lr.Attributes().PutInt("tenant", 42)
+
+	extensions := NewExtensions([]ExtensionConfig{
+		{Name: "env", Value: "prod"},
+		{Name: "cluster", From: "k8s.cluster.name"},
+		{Name: "tenant", From: "attributes.tenant"},
+		{Name: "team", From: "team"},
+	})
+
+	assert.Equal(t, []string{"prod", "east-1", "42", ""}, AppendExtensionValues(nil, extensions, res, lr))
+}
@@ -0,0 +1,191 @@
+package cloudevent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// How the values of a filter rule are compared with the field
+	MATCH_EXACT  = "exact"
+	MATCH_PREFIX = "prefix"
+	MATCH_GLOB   = "glob" // `*` matches any number of characters and `?` matches a single character
+	MATCH_REGEX  = "regex"
+)
+
+/*
+FiltersConfig selects the logs which are converted, a log is kept if it matches any of the include
+rules (or there aren't any) and doesn't match any of the exclude rules, so exclude takes priority
+*/
+type FiltersConfig struct {
+	Include []FilterRule `mapstructure:"include"`
+	Exclude []FilterRule `mapstructure:"exclude"`
+}
+
+// FilterRule matches a log if the field matches any of the values
+type FilterRule struct {
+	Field  string   `mapstructure:"field"` // same format as mapping fields, also allows severity_text and severity_number
+	Match  string   `mapstructure:"match"` // exact (default), prefix, glob or regex
+	Values []string `mapstructure:"values"`
+}
+
+type Matcher struct {
+	field  FieldRef
+	exact  map[string]struct{}
+	prefix []string
+	regex  *regexp.Regexp // glob and regex values are combined in a single expression
+}
+
+type LogFilter struct {
+	include []Matcher
+	exclude []Matcher
+}
+
+// Validate checks if the rules can be compiled
+func (cfg *FiltersConfig) Validate() error {
+	_, err := NewLogFilter(cfg)
+	return err
+}
+
+/*
+Compiles the configured rules, returns nil if there aren't any rules so that callers can skip
+filtering altogether
+*/
+func NewLogFilter(cfg *FiltersConfig) (*LogFilter, error) {
+	if len(cfg.Include) == 0 && len(cfg.Exclude) == 0 {
+		return nil, nil
+	}
+
+	f := &LogFilter{}
+	var err error
+
+	if f.include, err = NewMatchers("include", cfg.Include); err != nil {
+		return nil, err
+	}
+
+	if f.exclude, err = NewMatchers("exclude", cfg.Exclude); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func NewMatchers(name string, rules []FilterRule) ([]Matcher, error) {
+	ret := make([]Matcher, 0, len(rules))
+
+	for i, r := range rules {
+		if len(r.Field) == 0 {
+			return nil, fmt.Errorf("filters.%s[%d] field can not be empty", name, i)
+		}
+
+		if len(r.Values) == 0 {
+			return nil, fmt.Errorf("filters.%s[%d] needs at least one value", name, i)
+		}
+
+		rule := Matcher{field: NewFieldRef(r.Field)}
+		var expressions []string
+
+		switch r.Match {
+		case "", MATCH_EXACT:
+			rule.exact = make(map[string]struct{}, len(r.Values))
+			for _, v := range r.Values {
+				rule.exact[v] = struct{}{}
+			}
+		case MATCH_PREFIX:
+			rule.prefix = r.Values
+		case MATCH_GLOB:
+			for _, v := range r.Values {
+				expressions = append(expressions, globToRegex(v))
+			}
+		case MATCH_REGEX:
+			for _, v := range r.Values {
+				if _, err := regexp.Compile(v); err != nil {
+					return nil, fmt.Errorf("filters.%s[%d] has invalid regex '%s': %w", name, i, v, err)
+				}
+				expressions = append(expressions, "(?:"+v+")")
+			}
+		default:
+			return nil, fmt.Errorf("filters.%s[%d] match should be one of '%s', '%s', '%s' or '%s', provided: %s",
+				name, i, MATCH_EXACT, MATCH_PREFIX, MATCH_GLOB, MATCH_REGEX, r.Match,
+			)
+		}
+
+		if len(expressions) > 0 {
+			// Regex values aren't anchored, same as regexp.MatchString
+			expr := strings.Join(expressions, "|")
+			if r.Match == MATCH_GLOB {
+				expr = "^(?:" + expr + ")$"
+			}
+			rule.regex = regexp.MustCompile(expr)
+		}
+
+		ret = append(ret, rule)
+	}
+
+	return ret, nil
+}
+
+// Converts a glob like `kube-*` to the regex `kube-.*`, the caller anchors it
+func globToRegex(glob string) string {
+	var ret strings.Builder
+
+	for _, ch := range glob {
+		switch ch {
+		case '*':
+			ret.WriteString(".*")
+		case '?':
+			ret.WriteRune('.')
+		default:
+			ret.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	return ret.String()
+}
+
+// A rule doesn't match if the log doesn't have the field
+func (r *Matcher) Matches(res pcommon.Resource, lr plog.LogRecord) bool {
+	val, ok := r.field.Get(res, lr)
+	if !ok {
+		return false
+	}
+	str := val.AsString()
+
+	if r.exact != nil {
+		_, found := r.exact[str]
+		return found
+	}
+
+	for _, p := range r.prefix {
+		if strings.HasPrefix(str, p) {
+			return true
+		}
+	}
+
+	return r.regex != nil && r.regex.MatchString(str)
+}
+
+// Returns true if the log should be converted
+func (f *LogFilter) Keep(res pcommon.Resource, lr plog.LogRecord) bool {
+	for i := range f.exclude {
+		if f.exclude[i].Matches(res, lr) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for i := range f.include {
+		if f.include[i].Matches(res, lr) {
+			return true
+		}
+	}
+
+	return false
+}
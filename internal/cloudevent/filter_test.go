@@ -0,0 +1,113 @@
+package cloudevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestLogFilter(t *testing.T) {
+	res := pcommon.NewResource()
+	res.Attributes().PutStr("k8s.object.kind", "Pod")
+	lr := plog.NewLogRecord()
+	lr.Attributes().PutStr(ATTR_EVENT_NS, "kube-system")
+	lr.Attributes().PutStr(ATTR_EVENT_REASON, "BackOff")
+	lr.SetSeverityText("Warning")
+	lr.SetSeverityNumber(plog.SeverityNumberWarn)
+
+	tests := []struct {
+		name string
+		cfg  FiltersConfig
+		keep bool
+	}{
+		{
+			name: "no include rules",
+			cfg:  FiltersConfig{Exclude: []FilterRule{{Field: ATTR_EVENT_NS, Values: []string{"default"}}}},
+			keep: true,
+		},
+		{
+			name: "exact",
+			cfg:  FiltersConfig{Include: []FilterRule{{Field: ATTR_EVENT_REASON, Values: []string{"Pulled", "BackOff"}}}},
+			keep: true,
+		},
+		{
+			name: "exact no match",
+			cfg:  FiltersConfig{Include: []FilterRule{{Field: ATTR_EVENT_REASON, Match: MATCH_EXACT, Values: []string{"Back"}}}},
+			keep: false,
+		},
+		{
+			name: "prefix",
+			cfg:  FiltersConfig{Exclude: []FilterRule{{Field: ATTR_EVENT_NS, Match: MATCH_PREFIX, Values: []string{"kube-"}}}},
+			keep: false,
+		},
+		{
+			name: "glob",
+			cfg:  FiltersConfig{Exclude: []FilterRule{{Field: ATTR_EVENT_NS, Match: MATCH_GLOB, Values: []string{"kube-sys*m"}}}},
+			keep: false,
+		},
+		{
+			name: "glob is anchored",
+			cfg:  FiltersConfig{Exclude: []FilterRule{{Field: ATTR_EVENT_NS, Match: MATCH_GLOB, Values: []string{"kube"}}}},
+			keep: true,
+		},
+		{
+			name: "regex",
+			cfg:  FiltersConfig{Include: []FilterRule{{Field: ATTR_EVENT_REASON, Match: MATCH_REGEX, Values: []string{"^Back(Off|Down)$"}}}},
+			keep: true,
+		},
+		{
+			name: "resource attribute",
+			cfg:  FiltersConfig{Include: []FilterRule{{Field: "resource.k8s.object.kind", Values: []string{"Node"}}}},
+			keep: false,
+		},
+		{
+			name: "severity",
+			cfg: FiltersConfig{Include: []FilterRule{
+				{Field: FIELD_SEVERITY_TEXT, Values: []string{"Normal"}},
+				{Field: FIELD_SEVERITY_NUMBER, Values: []string{"13"}},
+			}},
+			keep: true,
+		},
+		{
+			name: "missing field",
+			cfg:  FiltersConfig{Include: []FilterRule{{Field: "k8s.node.name", Match: MATCH_GLOB, Values: []string{"*"}}}},
+			keep: false,
+		},
+		{
+			name: "exclude has priority",
+			cfg: FiltersConfig{
+				Include: []FilterRule{{Field: FIELD_SEVERITY_TEXT, Values: []string{"Warning"}}},
+				Exclude: []FilterRule{{Field: ATTR_EVENT_NS, Values: []string{"kube-system"}}},
+			},
+			keep: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewLogFilter(&tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.keep, f.Keep(res, lr))
+		})
+	}
+}
+
+func TestLogFilterValidation(t *testing.T) {
+	f, err := NewLogFilter(&FiltersConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	invalid := []FiltersConfig{
+		{Include: []FilterRule{{Values: []string{"a"}}}},
+		{Include: []FilterRule{{Field: "a"}}},
+		{Exclude: []FilterRule{{Field: "a", Match: "contains", Values: []string{"a"}}}},
+		{Exclude: []FilterRule{{Field: "a", Match: MATCH_REGEX, Values: []string{"("}}}},
+	}
+
+	for _, cfg := range invalid {
+		assert.Error(t, cfg.Validate())
+	}
+}
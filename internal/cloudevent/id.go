@@ -0,0 +1,108 @@
+package cloudevent
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	// How the CloudEvent id is formed
+	ID_STRATEGY_ATTRIBUTE = "attribute" // field configured in mapping.id (k8s.event.uid by default)
+	ID_STRATEGY_UUIDV4    = "uuidv4"    // random UUID
+	ID_STRATEGY_UUIDV7    = "uuidv7"    // time ordered random UUID
+	ID_STRATEGY_HASH      = "hash"      // SHA-256 of mapping.id_hash_fields, same record gives the same id
+)
+
+// Checks if the id strategy is a known one
+func validateIDStrategy(strategy string) error {
+	switch strategy {
+	case "", ID_STRATEGY_ATTRIBUTE, ID_STRATEGY_UUIDV4, ID_STRATEGY_UUIDV7, ID_STRATEGY_HASH:
+		return nil
+	}
+
+	return fmt.Errorf("mapping.id_strategy should be one of '%s', '%s', '%s' or '%s', provided: %s",
+		ID_STRATEGY_ATTRIBUTE, ID_STRATEGY_UUIDV4, ID_STRATEGY_UUIDV7, ID_STRATEGY_HASH, strategy)
+}
+
+// Random (version 4) UUID as per RFC 9562
+func NewUUIDv4() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:]) // crypto/rand doesn't fail on the supported platforms
+
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u)
+}
+
+// Time ordered (version 7) UUID as per RFC 9562, the first 48 bits are the unix time in milliseconds
+func newUUIDv7(now time.Time) string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+
+	ms := uint64(now.UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+
+	u[6] = (u[6] & 0x0f) | 0x70
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u)
+}
+
+/*
+UUID formed from the first 16 bytes of the SHA-256 of the values, with version 8 (custom) so that it's still
+a valid UUID. Values are written with their type and length so that different values can't give the same input
+*/
+func NewHashID(values []pcommon.Value) string {
+	h := sha256.New()
+	for _, val := range values {
+		writeHashValue(h, val)
+	}
+
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+
+	u[6] = (u[6] & 0x0f) | 0x80
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u)
+}
+
+func writeHashValue(h hash.Hash, val pcommon.Value) {
+	var raw []byte
+	if val.Type() == pcommon.ValueTypeBytes {
+		raw = val.Bytes().AsRaw()
+	} else {
+		// Maps and slices are written as JSON, which keeps the keys in their order
+		raw = []byte(val.AsString())
+	}
+
+	var header [9]byte
+	header[0] = byte(val.Type())
+	binary.BigEndian.PutUint64(header[1:], uint64(len(raw)))
+	h.Write(header[:])
+	h.Write(raw)
+}
+
+func formatUUID(u [16]byte) string {
+	var ret [36]byte
+	hex.Encode(ret[0:8], u[0:4])
+	ret[8] = '-'
+	hex.Encode(ret[9:13], u[4:6])
+	ret[13] = '-'
+	hex.Encode(ret[14:18], u[6:8])
+	ret[18] = '-'
+	hex.Encode(ret[19:23], u[8:10])
+	ret[23] = '-'
+	hex.Encode(ret[24:], u[10:])
+	return string(ret[:])
+}
@@ -0,0 +1,81 @@
+package cloudevent
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([0-9a-f])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestValidateIDStrategy(t *testing.T) {
+	for _, s := range []string{"", ID_STRATEGY_ATTRIBUTE, ID_STRATEGY_UUIDV4, ID_STRATEGY_UUIDV7, ID_STRATEGY_HASH} {
+		assert.NoError(t, validateIDStrategy(s), s)
+	}
+	assert.Error(t, validateIDStrategy("uuid"))
+
+	// mapping.id is needed only by the attribute strategy
+	assert.Error(t, (&MappingConfig{TypeSuffix: ATTR_EVENT_REASON}).Validate())
+	assert.NoError(t, (&MappingConfig{TypeSuffix: ATTR_EVENT_REASON, IDStrategy: ID_STRATEGY_UUIDV4}).Validate())
+	assert.Error(t, (&MappingConfig{TypeSuffix: ATTR_EVENT_REASON, IDStrategy: ID_STRATEGY_HASH, IDHashFields: []string{""}}).Validate())
+}
+
+func TestUUIDs(t *testing.T) {
+	v4 := NewUUIDv4()
+	assert.Equal(t, "4", uuidPattern.FindStringSubmatch(v4)[1])
+	assert.NotEqual(t, v4, NewUUIDv4())
+
+	now := time.UnixMilli(0x0188_1234_5678)
+	v7 := newUUIDv7(now)
+	assert.Equal(t, "7", uuidPattern.FindStringSubmatch(v7)[1])
+	assert.Equal(t, "01881234-5678-7", v7[:15])
+	assert.Less(t, newUUIDv7(now), newUUIDv7(now.Add(time.Millisecond)))
+}
+
+func TestHashID(t *testing.T) {
+	id := NewHashID([]pcommon.Value{pcommon.NewValueStr("a"), pcommon.NewValueInt(1)})
+	assert.Equal(t, "8", uuidPattern.FindStringSubmatch(id)[1])
+	assert.Equal(t, id, NewHashID([]pcommon.Value{pcommon.NewValueStr("a"), pcommon.NewValueInt(1)}))
+
+	// Values are separated and typed so that these don't collide
+	assert.NotEqual(t, id, NewHashID([]pcommon.Value{pcommon.NewValueStr("a1")}))
+	assert.NotEqual(t, id, NewHashID([]pcommon.Value{pcommon.NewValueStr("a"), pcommon.NewValueStr("1")}))
+	assert.NotEqual(t,
+		NewHashID([]pcommon.Value{pcommon.NewValueEmpty()}),
+		NewHashID([]pcommon.Value{pcommon.NewValueStr("")}))
+}
+
+func TestMappingIDStrategies(t *testing.T) {
+	res := pcommon.NewResource()
+	lr := plog.NewLogRecord()
+	lr.Attributes().PutStr(ATTR_EVENT_REASON, "BackOff")
+	lr.Attributes().PutStr("request.id", "r-1")
+	lr.Body().SetStr("failed")
+
+	extract := func(cfg MappingConfig) (*Event, []string) {
+		cfg.TypeSuffix = ATTR_EVENT_REASON
+		cfg.Data = []DataFieldMapping{{Key: "message", From: FIELD_BODY}}
+		ev := &Event{}
+		return ev, NewMapping(&cfg, nil).Extract(res, lr, ev)
+	}
+
+	// uid is missing, only the attribute strategy needs it
+	_, missing := extract(MappingConfig{ID: ATTR_EVENT_UID})
+	assert.Equal(t, []string{ATTR_EVENT_UID}, missing)
+
+	ev, missing := extract(MappingConfig{ID: ATTR_EVENT_UID, IDStrategy: ID_STRATEGY_UUIDV7})
+	assert.Empty(t, missing)
+	assert.Regexp(t, uuidPattern, ev.ID)
+
+	first, _ := extract(MappingConfig{IDStrategy: ID_STRATEGY_HASH})
+	second, _ := extract(MappingConfig{IDStrategy: ID_STRATEGY_HASH})
+	assert.Equal(t, first.ID, second.ID)
+
+	byRequest, _ := extract(MappingConfig{IDStrategy: ID_STRATEGY_HASH, IDHashFields: []string{"request.id"}})
+	assert.Equal(t, NewHashID([]pcommon.Value{pcommon.NewValueStr("r-1")}), byRequest.ID)
+	assert.NotEqual(t, first.ID, byRequest.ID)
+}
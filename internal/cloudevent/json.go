@@ -0,0 +1,181 @@
+package cloudevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const HEX_DIGITS = "0123456789abcdef"
+
+/*
+Appends `"key":"val"` to retSlice, both are escaped as per RFC 8259
+Ex: key `message` and value `say "hi"` become `"message":"say \"hi\""`
+*/
+func AppendJsonObjStr(key string, val string, retSlice []byte) []byte {
+	return AppendJsonStr(val, AppendJsonObjElse(key, nil, retSlice))
+}
+
+/*
+Appends `"key":val` to retSlice, key is escaped and value is written as is
+Ex: key `count` and value `3` become `"count":3`
+*/
+func AppendJsonObjElse(key string, val []byte, retSlice []byte) []byte {
+	retSlice = AppendJsonStr(key, retSlice)
+	retSlice = append(retSlice, COLON_BYTE)
+
+	return append(retSlice, val...)
+}
+
+/*
+Appends `"key":val` to retSlice, value is written as per its type
+Ex: string `val` will become `"val"`, int `3` will remain `3` and an empty value will become `null`
+*/
+func AppendJsonObjValue(key string, val pcommon.Value, retSlice []byte) []byte {
+	return AppendJsonValue(val, AppendJsonObjElse(key, nil, retSlice))
+}
+
+/*
+Appends the string in quotes escaping what RFC 8259 requires, the ASCII characters which don't need
+escaping are copied in chunks. Control characters use the short escapes (`\n`, `\t`, ...) when there's one
+and `\u00XX` otherwise, invalid UTF-8 is replaced by U+FFFD and U+2028/U+2029 are escaped as they
+end the line in JavaScript
+*/
+func AppendJsonStr(val string, retSlice []byte) []byte {
+	retSlice = append(retSlice, QUOTE_BYTE)
+
+	start := 0
+	for i := 0; i < len(val); {
+		if ch := val[i]; ch < utf8.RuneSelf {
+			if ch >= 0x20 && ch != QUOTE_BYTE && ch != BACKSLASH_BYTE {
+				i++
+				continue
+			}
+
+			retSlice = append(retSlice, val[start:i]...)
+			switch ch {
+			case QUOTE_BYTE, BACKSLASH_BYTE:
+				retSlice = append(retSlice, BACKSLASH_BYTE, ch)
+			case '\n':
+				retSlice = append(retSlice, BACKSLASH_BYTE, 'n')
+			case '\r':
+				retSlice = append(retSlice, BACKSLASH_BYTE, 'r')
+			case '\t':
+				retSlice = append(retSlice, BACKSLASH_BYTE, 't')
+			case '\b':
+				retSlice = append(retSlice, BACKSLASH_BYTE, 'b')
+			case '\f':
+				retSlice = append(retSlice, BACKSLASH_BYTE, 'f')
+			default:
+				retSlice = append(retSlice, BACKSLASH_BYTE, 'u', '0', '0', HEX_DIGITS[ch>>4], HEX_DIGITS[ch&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(val[i:])
+		if r == utf8.RuneError && size == 1 {
+			retSlice = append(retSlice, val[start:i]...)
+			retSlice = append(retSlice, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+
+		if r == '\u2028' || r == '\u2029' {
+			retSlice = append(retSlice, val[start:i]...)
+			retSlice = append(retSlice, BACKSLASH_BYTE, 'u', '2', '0', '2', HEX_DIGITS[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+	retSlice = append(retSlice, val[start:]...)
+
+	return append(retSlice, QUOTE_BYTE)
+}
+
+/*
+Appends the value as per its type, maps become JSON objects and slices become JSON arrays
+Bytes are written as base64 string, same as their string representation
+*/
+func AppendJsonValue(val pcommon.Value, retSlice []byte) []byte {
+	switch val.Type() {
+	case pcommon.ValueTypeStr:
+		return AppendJsonStr(val.Str(), retSlice)
+	case pcommon.ValueTypeInt:
+		return strconv.AppendInt(retSlice, val.Int(), 10)
+	case pcommon.ValueTypeDouble:
+		f := val.Double()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			// JSON doesn't have a representation of these
+			return AppendJsonStr(val.AsString(), retSlice)
+		}
+		return strconv.AppendFloat(retSlice, f, 'g', -1, 64)
+	case pcommon.ValueTypeBool:
+		return strconv.AppendBool(retSlice, val.Bool())
+	case pcommon.ValueTypeEmpty:
+		return append(retSlice, "null"...)
+	case pcommon.ValueTypeMap:
+		return AppendJsonMap(val.Map(), retSlice)
+	case pcommon.ValueTypeSlice:
+		retSlice = append(retSlice, OPEN_BRACKET_BYTE)
+		for i := 0; i < val.Slice().Len(); i++ {
+			if i > 0 {
+				retSlice = append(retSlice, COMMA_BYTE)
+			}
+			retSlice = AppendJsonValue(val.Slice().At(i), retSlice)
+		}
+		return append(retSlice, CLOSE_BRACKET_BYTE)
+	}
+
+	return AppendJsonStr(val.AsString(), retSlice)
+}
+
+// Appends the map as JSON object, keys are kept in the same order as the map
+func AppendJsonMap(m pcommon.Map, retSlice []byte) []byte {
+	first := true
+
+	retSlice = append(retSlice, OPEN_BRACE_BYTE)
+	m.Range(func(k string, v pcommon.Value) bool {
+		if !first {
+			retSlice = append(retSlice, COMMA_BYTE)
+		}
+		first = false
+
+		retSlice = AppendJsonObjValue(k, v, retSlice)
+		return true
+	})
+
+	return append(retSlice, CLOSE_BRACE_BYTE)
+}
+
+/*
+Strings which hold a JSON object or array (ex: `{"level":"info"}` from an application log) are appended
+as is after removing the insignificant whitespace, anything else is appended as a string
+json.Compact doesn't check UTF-8, so strings which aren't valid UTF-8 are appended as a string too
+*/
+func AppendJsonEmbedded(str string, retSlice []byte) []byte {
+	trimmed := strings.TrimSpace(str)
+	if len(trimmed) > 1 &&
+		((trimmed[0] == OPEN_BRACE_BYTE && trimmed[len(trimmed)-1] == CLOSE_BRACE_BYTE) ||
+			(trimmed[0] == OPEN_BRACKET_BYTE && trimmed[len(trimmed)-1] == CLOSE_BRACKET_BYTE)) &&
+		utf8.ValidString(trimmed) {
+		retLen := len(retSlice)
+		buf := bytes.NewBuffer(retSlice)
+		if err := json.Compact(buf, []byte(trimmed)); err == nil {
+			return buf.Bytes()
+		}
+		retSlice = buf.Bytes()[:retLen]
+	}
+
+	return AppendJsonStr(str, retSlice)
+}
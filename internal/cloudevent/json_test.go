@@ -0,0 +1,65 @@
+package cloudevent
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudeventtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestAppendJsonStrRoundTrip(t *testing.T) {
+	for _, str := range cloudeventtest.JsonTestCorpus() {
+		out := AppendJsonStr(str, nil)
+		require.True(t, json.Valid(out), "invalid JSON %q for %q", out, str)
+
+		var decoded string
+		require.NoError(t, json.Unmarshal(out, &decoded))
+		assert.Equal(t, cloudeventtest.ExpectedJsonStr(t, str), decoded, "input %q", str)
+	}
+}
+
+func TestAppendJsonStrEscapes(t *testing.T) {
+	assert.Equal(t, `"say \"hi\""`, string(AppendJsonStr(`say "hi"`, nil)))
+	assert.Equal(t, `"C:\\temp"`, string(AppendJsonStr(`C:\temp`, nil)))
+	assert.Equal(t, `"a\nb\rc\td\be\ff"`, string(AppendJsonStr("a\nb\rc\td\be\ff", nil)))
+	assert.Equal(t, `"\u0000\u001f\u000b"`, string(AppendJsonStr("\x00\x1f\v", nil)))
+	assert.Equal(t, `"\u2028\u2029"`, string(AppendJsonStr("\u2028\u2029", nil)))
+	assert.Equal(t, `"a\ufffdb"`, string(AppendJsonStr("a\xffb", nil)))
+	assert.Equal(t, `"ünïcödé 🚀"`, string(AppendJsonStr("ünïcödé 🚀", nil)))
+}
+
+func TestAppendJsonValueRoundTrip(t *testing.T) {
+	corpus := cloudeventtest.JsonTestCorpus()
+
+	val := pcommon.NewValueMap()
+	expected := map[string]interface{}{}
+	for i, str := range corpus {
+		// Keys have to be unique after the invalid UTF-8 is replaced
+		key := strings.Repeat("k", i%3) + str + string(rune('A'+i%26)) + strings.Repeat("#", i)
+		val.Map().PutStr(key, str)
+		expected[cloudeventtest.ExpectedJsonStr(t, key)] = cloudeventtest.ExpectedJsonStr(t, str)
+	}
+	slice := val.Map().PutEmptySlice("slice")
+	slice.AppendEmpty().SetStr("line\n")
+	slice.AppendEmpty().SetDouble(1.5)
+	slice.AppendEmpty()
+	expected["slice"] = []interface{}{"line\n", 1.5, nil}
+
+	out := AppendJsonValue(val, nil)
+	require.True(t, json.Valid(out), "invalid JSON %q", out)
+
+	decoded := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(out, &decoded))
+	assert.Equal(t, expected, decoded)
+}
+
+func TestAppendJsonEmbedded(t *testing.T) {
+	assert.Equal(t, `{"a":[1,2]}`, string(AppendJsonEmbedded(" {\"a\": [1, 2]}\n", nil)))
+	assert.Equal(t, `"{\"a\":"`, string(AppendJsonEmbedded(`{"a":`, nil)))
+	assert.Equal(t, `x"{\"a\"}"`, string(AppendJsonEmbedded(`{"a"}`, []byte("x"))))
+	assert.Equal(t, `"{\"a\":\"\ufffd\"}"`, string(AppendJsonEmbedded("{\"a\":\"\xff\"}", nil)))
+}
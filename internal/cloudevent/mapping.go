@@ -0,0 +1,374 @@
+package cloudevent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// Prefixes which select where a mapped field is read from, a field without any prefix
+	// is looked up in the log attributes first and then in the resource attributes
+	FIELD_PREFIX_ATTRIBUTES = "attributes."
+	FIELD_PREFIX_RESOURCE   = "resource."
+	FIELD_PREFIX_BODY       = "body."
+	FIELD_BODY              = "body"
+	FIELD_SEVERITY_TEXT     = "severity_text"   // event type (Normal/Warning) for k8s events
+	FIELD_SEVERITY_NUMBER   = "severity_number" // numeric severity of the log
+)
+
+const (
+	// Policies for a log record which doesn't have all the mapped fields
+	MISSING_ATTR_FAIL        = "fail"        // fail the whole batch
+	MISSING_ATTR_DROP        = "drop"        // drop the log record
+	MISSING_ATTR_PASSTHROUGH = "passthrough" // keep the log record as is, without converting it
+	MISSING_ATTR_DEFAULT     = "default"     // use the configured default value for the missing fields
+)
+
+const (
+	fieldSourceAny fieldSource = iota
+	fieldSourceAttributes
+	fieldSourceResource
+	fieldSourceBody
+	fieldSourceBodyKey
+	fieldSourceSeverityText
+	fieldSourceSeverityNumber
+)
+
+// MappingConfig defines which log attribute, resource attribute or body field fills the CloudEvent
+type MappingConfig struct {
+	ID         string             `mapstructure:"id"`
+	Subject    string             `mapstructure:"subject"`
+	Time       string             `mapstructure:"time"`
+	TypeSuffix string             `mapstructure:"type_suffix"`
+	Data       []DataFieldMapping `mapstructure:"data"` // keys of the data object in order, k8s event fields when empty
+
+	// Body (or body key) strings holding a JSON object or array are written in data as JSON instead of a string
+	ParseJSONBody bool `mapstructure:"parse_json_body"`
+	// Key of data under which the log attributes are written as an object, not written when empty
+	IncludeAttributes string `mapstructure:"include_attributes"`
+	// Field which is used as the whole data, data and include_attributes aren't used when it's set
+	DataFrom string `mapstructure:"data_from"`
+
+	// How the id is formed: attribute (default, from mapping.id), uuidv4, uuidv7 or hash
+	IDStrategy string `mapstructure:"id_strategy"`
+	// Fields hashed by the hash strategy, the type suffix and data values when empty
+	IDHashFields []string `mapstructure:"id_hash_fields"`
+}
+
+// DataFieldMapping maps one key of the CloudEvent data object
+type DataFieldMapping struct {
+	Key  string `mapstructure:"key"`
+	From string `mapstructure:"from"`
+}
+
+type fieldSource int
+
+// FieldRef is the parsed form of a mapped field like `k8s.event.uid`, `resource.host.name` or `body.message`
+type FieldRef struct {
+	name   string // as provided in configuration, used while reporting missing fields
+	key    string
+	source fieldSource
+}
+
+type DataField struct {
+	Key   string
+	field FieldRef
+	JSON  bool // string values holding JSON are written as is
+}
+
+type Mapping struct {
+	id         FieldRef // used only with ID_STRATEGY_ATTRIBUTE
+	idStrategy string
+	idHash     []FieldRef // fields of ID_STRATEGY_HASH, empty to hash the type suffix and data
+	subject    *FieldRef
+	time       *FieldRef // read by TimeResolver as it needs parsing
+	typeSuffix FieldRef
+	Data       []DataField
+	defaults   map[string]string // values for missing fields keyed by the configured field name
+
+	AttributesKey string      // data key for the log attributes, empty if they aren't included
+	DataFrom      *DataField  // field which is the whole data, nil if data is an object of m.data
+	Resource      []DataField // resource_attributes copied in data, not used with dataFrom
+}
+
+/*
+Data keys which are used when nothing is configured in mapping, these are the fields that
+k8seventsreceiver provides and were used as is before mapping was configurable
+*/
+func defaultDataMapping() []DataFieldMapping {
+	return []DataFieldMapping{
+		{Key: "reason", From: ATTR_EVENT_REASON},
+		{Key: "start_time", From: ATTR_EVENT_START_TIME},
+		{Key: "name", From: ATTR_EVENT_NAME},
+		{Key: "namespace", From: ATTR_EVENT_NS},
+		{Key: "count", From: ATTR_EVENT_COUNT},
+		{Key: "message", From: FIELD_BODY},
+	}
+}
+
+// Validate checks if the mapped fields are usable
+func (cfg *MappingConfig) Validate() error {
+	if err := validateIDStrategy(cfg.IDStrategy); err != nil {
+		return err
+	}
+
+	if len(cfg.ID) == 0 && (len(cfg.IDStrategy) == 0 || cfg.IDStrategy == ID_STRATEGY_ATTRIBUTE) {
+		return errors.New("mapping.id field can not be empty")
+	}
+
+	for _, f := range cfg.IDHashFields {
+		if len(f) == 0 {
+			return errors.New("mapping.id_hash_fields can not have empty fields")
+		}
+	}
+
+	if len(cfg.TypeSuffix) == 0 {
+		return errors.New("mapping.type_suffix field can not be empty")
+	}
+
+	dataCfg := cfg.Data
+	if len(dataCfg) == 0 {
+		dataCfg = defaultDataMapping()
+	}
+
+	keys := make(map[string]bool, len(dataCfg))
+	for _, d := range dataCfg {
+		if len(d.Key) == 0 || len(d.From) == 0 {
+			return fmt.Errorf("mapping.data entries need both key and from, provided: key '%s' from '%s'", d.Key, d.From)
+		}
+
+		if keys[d.Key] {
+			return fmt.Errorf("mapping.data key '%s' is provided more than once", d.Key)
+		}
+		keys[d.Key] = true
+	}
+
+	if keys[cfg.IncludeAttributes] {
+		return fmt.Errorf("mapping.include_attributes key '%s' is already used in mapping.data", cfg.IncludeAttributes)
+	}
+
+	return nil
+}
+
+func NewFieldRef(name string) FieldRef {
+	switch {
+	case name == FIELD_BODY:
+		return FieldRef{name: name, source: fieldSourceBody}
+	case name == FIELD_SEVERITY_TEXT:
+		return FieldRef{name: name, source: fieldSourceSeverityText}
+	case name == FIELD_SEVERITY_NUMBER:
+		return FieldRef{name: name, source: fieldSourceSeverityNumber}
+	case strings.HasPrefix(name, FIELD_PREFIX_BODY):
+		return FieldRef{name: name, key: name[len(FIELD_PREFIX_BODY):], source: fieldSourceBodyKey}
+	case strings.HasPrefix(name, FIELD_PREFIX_ATTRIBUTES):
+		return FieldRef{name: name, key: name[len(FIELD_PREFIX_ATTRIBUTES):], source: fieldSourceAttributes}
+	case strings.HasPrefix(name, FIELD_PREFIX_RESOURCE):
+		return FieldRef{name: name, key: name[len(FIELD_PREFIX_RESOURCE):], source: fieldSourceResource}
+	}
+
+	return FieldRef{name: name, key: name, source: fieldSourceAny}
+}
+
+func newOptionalFieldRef(name string) *FieldRef {
+	if len(name) == 0 {
+		return nil
+	}
+
+	f := NewFieldRef(name)
+	return &f
+}
+
+func NewMapping(cfg *MappingConfig, defaults map[string]string) *Mapping {
+	m := &Mapping{
+		id:         NewFieldRef(cfg.ID),
+		idStrategy: cfg.IDStrategy,
+		subject:    newOptionalFieldRef(cfg.Subject),
+		time:       newOptionalFieldRef(cfg.Time),
+		typeSuffix: NewFieldRef(cfg.TypeSuffix),
+		defaults:   defaults,
+	}
+
+	if len(m.idStrategy) == 0 {
+		m.idStrategy = ID_STRATEGY_ATTRIBUTE
+	}
+
+	for _, f := range cfg.IDHashFields {
+		m.idHash = append(m.idHash, NewFieldRef(f))
+	}
+
+	if len(cfg.DataFrom) > 0 {
+		m.DataFrom = newDataField("", cfg.DataFrom, cfg.ParseJSONBody)
+		return m
+	}
+
+	dataCfg := cfg.Data
+	if len(dataCfg) == 0 {
+		dataCfg = defaultDataMapping()
+	}
+
+	m.Data = make([]DataField, 0, len(dataCfg))
+	m.AttributesKey = cfg.IncludeAttributes
+	for _, d := range dataCfg {
+		m.Data = append(m.Data, *newDataField(d.Key, d.From, cfg.ParseJSONBody))
+	}
+
+	return m
+}
+
+func newDataField(key string, from string, parseJSONBody bool) *DataField {
+	f := &DataField{Key: key, field: NewFieldRef(from)}
+	f.JSON = parseJSONBody && (f.field.source == fieldSourceBody || f.field.source == fieldSourceBodyKey)
+	return f
+}
+
+// get looks up the field in the log record or its resource
+func (f *FieldRef) Get(res pcommon.Resource, lr plog.LogRecord) (pcommon.Value, bool) {
+	switch f.source {
+	case fieldSourceAttributes:
+		return lr.Attributes().Get(f.key)
+	case fieldSourceResource:
+		return res.Attributes().Get(f.key)
+	case fieldSourceBody:
+		return lr.Body(), true
+	case fieldSourceBodyKey:
+		if lr.Body().Type() != pcommon.ValueTypeMap {
+			return pcommon.Value{}, false
+		}
+		return lr.Body().Map().Get(f.key)
+	case fieldSourceSeverityText:
+		return pcommon.NewValueStr(lr.SeverityText()), true
+	case fieldSourceSeverityNumber:
+		return pcommon.NewValueInt(int64(lr.SeverityNumber())), true
+	}
+
+	if val, ok := lr.Attributes().Get(f.key); ok {
+		return val, true
+	}
+	return res.Attributes().Get(f.key)
+}
+
+/*
+Fills the Event from the log record using the mapping, data values are kept in the same
+order as m.data, or it's the only value when data_from is set. The returned slice has the names of the
+required fields which couldn't be found, those are filled with their default value so that the caller
+can decide what to do with the record. Without a default a missing id is a random UUID (the spec needs
+one), a missing data value is null and the type suffix is empty
+*/
+func (m *Mapping) Extract(res pcommon.Resource, lr plog.LogRecord, ev *Event) []string {
+	var missing []string
+
+	ev.Data = ev.Data[:0]
+	ev.Subject = ""
+	ev.Attributes = lr.Attributes()
+
+	if m.idStrategy == ID_STRATEGY_ATTRIBUTE {
+		if val, ok := m.id.Get(res, lr); ok {
+			ev.ID = val.AsString()
+		} else {
+			missing = appendMissing(missing, m.id.name)
+			if ev.ID = m.defaults[m.id.name]; len(ev.ID) == 0 {
+				ev.ID = NewUUIDv4()
+			}
+		}
+	}
+
+	if val, ok := m.typeSuffix.Get(res, lr); ok {
+		ev.TypeSuffix = val.AsString()
+	} else {
+		missing = appendMissing(missing, m.typeSuffix.name)
+		ev.TypeSuffix = m.defaults[m.typeSuffix.name]
+	}
+
+	if m.subject != nil {
+		if val, ok := m.subject.Get(res, lr); ok {
+			ev.Subject = val.AsString()
+		}
+	}
+
+	for i := range m.Data {
+		val, ok := m.Data[i].field.Get(res, lr)
+		if !ok {
+			missing = appendMissing(missing, m.Data[i].field.name)
+			val = m.defaultValue(m.Data[i].field.name)
+		}
+		ev.Data = append(ev.Data, val)
+	}
+	ev.Resource = appendResourceValues(ev.Resource[:0], m.Resource, res, lr)
+
+	if m.DataFrom != nil {
+		val, ok := m.DataFrom.field.Get(res, lr)
+		if !ok {
+			missing = appendMissing(missing, m.DataFrom.field.name)
+			val = m.defaultValue(m.DataFrom.field.name)
+		}
+		ev.Data = append(ev.Data, val)
+	}
+
+	switch m.idStrategy {
+	case ID_STRATEGY_UUIDV4:
+		ev.ID = NewUUIDv4()
+	case ID_STRATEGY_UUIDV7:
+		ev.ID = newUUIDv7(time.Now())
+	case ID_STRATEGY_HASH:
+		ev.ID = m.hashID(res, lr, ev)
+	}
+
+	return missing
+}
+
+// Configured default of a missing data field, null if there isn't one rather than an empty string in place of a number
+func (m *Mapping) defaultValue(name string) pcommon.Value {
+	if def, ok := m.defaults[name]; ok {
+		return pcommon.NewValueStr(def)
+	}
+	return pcommon.NewValueEmpty()
+}
+
+// Hashes the id_hash_fields, or the type suffix and data when there aren't any, missing fields are hashed as empty
+func (m *Mapping) hashID(res pcommon.Resource, lr plog.LogRecord, ev *Event) string {
+	if len(m.idHash) == 0 {
+		values := make([]pcommon.Value, 0, len(ev.Data)+1)
+		values = append(values, pcommon.NewValueStr(ev.TypeSuffix))
+		return NewHashID(append(values, ev.Data...))
+	}
+
+	values := make([]pcommon.Value, 0, len(m.idHash))
+	for i := range m.idHash {
+		val, ok := m.idHash[i].Get(res, lr)
+		if !ok {
+			val = pcommon.NewValueEmpty()
+		}
+		values = append(values, val)
+	}
+
+	return NewHashID(values)
+}
+
+// Checks if the policy is a known one, exporters can't pass through a log as is so they can disallow it
+func ValidateMissingAttrPolicy(policy string, allowPassthrough bool) error {
+	switch policy {
+	case "", MISSING_ATTR_FAIL, MISSING_ATTR_DROP, MISSING_ATTR_DEFAULT:
+		return nil
+	case MISSING_ATTR_PASSTHROUGH:
+		if allowPassthrough {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("on_missing_attributes value '%s' is not supported", policy)
+}
+
+// Same field can be mapped to multiple places, report it only once
+func appendMissing(missing []string, name string) []string {
+	for _, m := range missing {
+		if m == name {
+			return missing
+		}
+	}
+	return append(missing, name)
+}
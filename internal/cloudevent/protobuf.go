@@ -0,0 +1,147 @@
+package cloudevent
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// Formats of a structured CloudEvent
+	FORMAT_JSON     = "json"
+	FORMAT_PROTOBUF = "protobuf"
+
+	CONTENT_TYPE_PROTOBUF       = "application/cloudevents+protobuf"
+	CONTENT_TYPE_BATCH_PROTOBUF = "application/cloudevents-batch+protobuf"
+
+	// Field numbers of CloudEvent in cloudevents.proto of the spec
+	PROTO_FIELD_ID           protowire.Number = 1
+	PROTO_FIELD_SOURCE       protowire.Number = 2
+	PROTO_FIELD_SPEC_VERSION protowire.Number = 3
+	PROTO_FIELD_TYPE         protowire.Number = 4
+	PROTO_FIELD_ATTRIBUTES   protowire.Number = 5 // map<string, CloudEventAttributeValue>
+	PROTO_FIELD_BINARY_DATA  protowire.Number = 6
+	PROTO_FIELD_TEXT_DATA    protowire.Number = 7
+
+	// Field numbers of the map entries and of CloudEventAttributeValue (oneof attr)
+	PROTO_FIELD_MAP_KEY      protowire.Number = 1
+	PROTO_FIELD_MAP_VALUE    protowire.Number = 2
+	PROTO_FIELD_CE_STRING    protowire.Number = 3
+	PROTO_FIELD_CE_URI       protowire.Number = 5
+	PROTO_FIELD_CE_TIMESTAMP protowire.Number = 7
+
+	// Field of CloudEventBatch, every event is a repeated CloudEvent
+	PROTO_FIELD_BATCH_EVENTS protowire.Number = 1
+
+	// Fields of google.protobuf.Timestamp
+	PROTO_FIELD_SECONDS protowire.Number = 1
+	PROTO_FIELD_NANOS   protowire.Number = 2
+)
+
+// The protobuf format is only defined for 1.0, empty format means json
+func ValidateFormat(format string, specVersion string) error {
+	switch format {
+	case "", FORMAT_JSON:
+	case FORMAT_PROTOBUF:
+		if len(specVersion) > 0 && specVersion != SPEC_VERSION_10 {
+			return fmt.Errorf("format '%s' needs spec_version '%s', provided: %s", FORMAT_PROTOBUF, SPEC_VERSION_10, specVersion)
+		}
+	default:
+		return fmt.Errorf("format must be one of '%s' or '%s', provided: %s", FORMAT_JSON, FORMAT_PROTOBUF, format)
+	}
+	return nil
+}
+
+/*
+Appends a string field, protobuf strings have to be valid UTF-8 so invalid sequences are replaced
+by U+FFFD the same as in JSON (valid strings aren't copied)
+*/
+func AppendProtoString(b []byte, num protowire.Number, val string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, strings.ToValidUTF8(val, "\uFFFD"))
+}
+
+/*
+Appends an entry of CloudEvent.attributes with a string value, kind is the field of CloudEventAttributeValue
+the value is set in (ce_string or ce_uri)
+Ex: `subject` and `pod-1` become {key: "subject", value: {ce_string: "pod-1"}}
+*/
+func AppendProtoAttribute(b []byte, name string, kind protowire.Number, val string) []byte {
+	val = strings.ToValidUTF8(val, "\uFFFD")
+	return append(appendProtoAttributeHead(b, name, kind, len(val)), val...)
+}
+
+/*
+Appends the time attribute as ce_timestamp, val is the RFC 3339 time formed by the time resolver
+so it's always valid, it's left out if it isn't
+*/
+func AppendProtoTimeAttribute(b []byte, name string, val string) []byte {
+	t, err := time.Parse(time.RFC3339Nano, val)
+	if err != nil {
+		return b
+	}
+
+	var tsArr [24]byte // a Timestamp is at most 2 tags, a 10 byte and a 5 byte varint
+	ts := tsArr[:0]
+	if secs := t.Unix(); secs != 0 {
+		ts = protowire.AppendTag(ts, PROTO_FIELD_SECONDS, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(secs))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		ts = protowire.AppendTag(ts, PROTO_FIELD_NANOS, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(nanos))
+	}
+
+	return append(appendProtoAttributeHead(b, name, PROTO_FIELD_CE_TIMESTAMP, len(ts)), ts...)
+}
+
+/*
+Appends an entry of CloudEvent.attributes up to the length of the CloudEventAttributeValue field (kind),
+the size bytes of the value have to be appended right after
+*/
+func appendProtoAttributeHead(b []byte, name string, kind protowire.Number, size int) []byte {
+	name = strings.ToValidUTF8(name, "\uFFFD")
+
+	valueSize := protowire.SizeTag(kind) + protowire.SizeBytes(size)
+	entrySize := protowire.SizeTag(PROTO_FIELD_MAP_KEY) + protowire.SizeBytes(len(name)) +
+		protowire.SizeTag(PROTO_FIELD_MAP_VALUE) + protowire.SizeBytes(valueSize)
+
+	b = protowire.AppendTag(b, PROTO_FIELD_ATTRIBUTES, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(entrySize))
+	b = protowire.AppendTag(b, PROTO_FIELD_MAP_KEY, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, PROTO_FIELD_MAP_VALUE, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(valueSize))
+	b = protowire.AppendTag(b, kind, protowire.BytesType)
+	return protowire.AppendVarint(b, uint64(size))
+}
+
+/*
+Makes the bytes written after start a length delimited value by putting their length before them,
+so that a field (ex: the data) can be encoded in place without knowing its size up front
+*/
+func InsertProtoLength(b []byte, start int) []byte {
+	n := len(b) - start
+	size := protowire.SizeVarint(uint64(n))
+
+	b = append(b, make([]byte, size)...)
+	copy(b[start+size:], b[start:start+n])
+	protowire.AppendVarint(b[:start], uint64(n))
+
+	return b
+}
+
+/*
+Replaces invalid UTF-8 in the bytes written after start, so that they can be the value of a string field
+Ex: a JSON body embedded as is in data, which JSON itself doesn't check
+*/
+func ValidUTF8After(b []byte, start int) []byte {
+	if utf8.Valid(b[start:]) {
+		return b
+	}
+	return append(b[:start], bytes.ToValidUTF8(b[start:], []byte("\uFFFD"))...)
+}
@@ -0,0 +1,63 @@
+package cloudevent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hv/akash.chandra/cloudeventtransform/internal/cloudeventtest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestValidateFormat(t *testing.T) {
+	assert.NoError(t, ValidateFormat("", SPEC_VERSION_03))
+	assert.NoError(t, ValidateFormat(FORMAT_JSON, SPEC_VERSION_03))
+	assert.NoError(t, ValidateFormat(FORMAT_PROTOBUF, ""))
+	assert.NoError(t, ValidateFormat(FORMAT_PROTOBUF, SPEC_VERSION_10))
+	assert.Error(t, ValidateFormat(FORMAT_PROTOBUF, SPEC_VERSION_03))
+	assert.Error(t, ValidateFormat("avro", ""))
+}
+
+func TestInsertProtoLength(t *testing.T) {
+	// Lengths which take one and two bytes
+	for _, data := range []string{"", "text", strings.Repeat("a", 300)} {
+		b := protowire.AppendTag([]byte("head"), PROTO_FIELD_TEXT_DATA, protowire.BytesType)
+		start := len(b)
+		b = InsertProtoLength(append(b, data...), start)
+
+		expected := protowire.AppendTag([]byte("head"), PROTO_FIELD_TEXT_DATA, protowire.BytesType)
+		assert.Equal(t, protowire.AppendString(expected, data), b)
+	}
+}
+
+func TestValidUTF8After(t *testing.T) {
+	// Only what's after start is checked
+	assert.Equal(t, "\xff\"ok\"", string(ValidUTF8After([]byte("\xff\"ok\""), 1)))
+	assert.Equal(t, "\xff\"a�b\"", string(ValidUTF8After([]byte("\xff\"a\xffb\""), 1)))
+}
+
+func TestProtoAttributes(t *testing.T) {
+	var b []byte
+	b = AppendProtoString(b, PROTO_FIELD_ID, "abc")
+	b = AppendProtoString(b, PROTO_FIELD_TYPE, "bad \xff utf-8")
+	b = AppendProtoAttribute(b, "subject", PROTO_FIELD_CE_STRING, "pod-1")
+	b = AppendProtoAttribute(b, "dataschema", PROTO_FIELD_CE_URI, "https://example.com/schema")
+	b = AppendProtoAttribute(b, "long", PROTO_FIELD_CE_STRING, strings.Repeat("a", 200))
+	b = AppendProtoTimeAttribute(b, "time", "2023-03-01T10:00:00.5Z")
+	b = AppendProtoTimeAttribute(b, "epoch", "1970-01-01T00:00:00Z")
+	b = AppendProtoTimeAttribute(b, "invalid", "yesterday")
+
+	event := cloudeventtest.UnmarshalProtoEvent(t, b)
+	assert.Equal(t, "abc", event.ID)
+	assert.Equal(t, "bad � utf-8", event.Type)
+
+	attrs := event.Attributes
+	assert.Len(t, attrs, 5)
+	assert.Equal(t, "pod-1", attrs["subject"].Str)
+	assert.Equal(t, "https://example.com/schema", attrs["dataschema"].URI)
+	assert.Equal(t, strings.Repeat("a", 200), attrs["long"].Str)
+	assert.Equal(t, time.Date(2023, 3, 1, 10, 0, 0, 5e8, time.UTC), *attrs["time"].Timestamp)
+	assert.Equal(t, time.Unix(0, 0).UTC(), *attrs["epoch"].Timestamp)
+	assert.NotContains(t, attrs, "invalid")
+}
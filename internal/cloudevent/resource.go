@@ -0,0 +1,122 @@
+package cloudevent
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// Where a resource attribute is copied to
+	RESOURCE_ATTR_TO_DATA      = "data"      // key of the data object
+	RESOURCE_ATTR_TO_EXTENSION = "extension" // extension attribute
+)
+
+/*
+ResourceAttributeConfig copies a resource attribute (ex: k8s.cluster.name) in every event, so that the events
+of many clusters or hosts can be told apart. It's left out when the resource doesn't have it
+*/
+type ResourceAttributeConfig struct {
+	From string `mapstructure:"from"` // resource attribute
+	Key  string `mapstructure:"key"`  // key in data (from when empty) or extension name (required)
+	To   string `mapstructure:"to"`   // data (default) or extension
+}
+
+// Checks the resource attributes copied in data, extensions are checked along with ce.extensions
+func ValidateResourceAttributes(attrs []ResourceAttributeConfig, mappingCfg *MappingConfig) error {
+	dataCfg := mappingCfg.Data
+	if len(dataCfg) == 0 {
+		dataCfg = defaultDataMapping()
+	}
+
+	keys := make(map[string]bool, len(dataCfg)+len(attrs))
+	for _, d := range dataCfg {
+		keys[d.Key] = true
+	}
+	if len(mappingCfg.IncludeAttributes) > 0 {
+		keys[mappingCfg.IncludeAttributes] = true
+	}
+
+	for _, attr := range attrs {
+		if len(attr.From) == 0 {
+			return fmt.Errorf("resource_attributes entries need from, provided: key '%s'", attr.Key)
+		}
+
+		switch attr.To {
+		case "", RESOURCE_ATTR_TO_DATA:
+		case RESOURCE_ATTR_TO_EXTENSION:
+			if len(attr.Key) == 0 {
+				return fmt.Errorf("resource_attributes entry '%s' needs key as the extension name", attr.From)
+			}
+			continue
+		default:
+			return fmt.Errorf("resource_attributes to should be one of '%s' or '%s', provided: %s",
+				RESOURCE_ATTR_TO_DATA, RESOURCE_ATTR_TO_EXTENSION, attr.To)
+		}
+
+		key := resourceDataKey(attr)
+		if keys[key] {
+			return fmt.Errorf("resource_attributes key '%s' is already used in data", key)
+		}
+		keys[key] = true
+	}
+
+	return nil
+}
+
+// Extensions of ce followed by the resource attributes copied to extensions, extensions isn't modified
+func WithResourceExtensions(extensions []ExtensionConfig, attrs []ResourceAttributeConfig) []ExtensionConfig {
+	ret := append([]ExtensionConfig(nil), extensions...)
+	for _, attr := range attrs {
+		if attr.To == RESOURCE_ATTR_TO_EXTENSION {
+			ret = append(ret, ExtensionConfig{Name: attr.Key, From: FIELD_PREFIX_RESOURCE + attr.From})
+		}
+	}
+	return ret
+}
+
+// Fields of the resource attributes copied in data, written after the other keys of data
+func NewResourceDataFields(attrs []ResourceAttributeConfig) []DataField {
+	var ret []DataField
+	for _, attr := range attrs {
+		if len(attr.To) == 0 || attr.To == RESOURCE_ATTR_TO_DATA {
+			ret = append(ret, DataField{Key: resourceDataKey(attr), field: NewFieldRef(FIELD_PREFIX_RESOURCE + attr.From)})
+		}
+	}
+	return ret
+}
+
+func resourceDataKey(attr ResourceAttributeConfig) string {
+	if len(attr.Key) > 0 {
+		return attr.Key
+	}
+	return attr.From
+}
+
+// Values of the resource data fields in the same order, empty values are left out of data
+func appendResourceValues(values []pcommon.Value, fields []DataField, res pcommon.Resource, lr plog.LogRecord) []pcommon.Value {
+	for i := range fields {
+		val, ok := fields[i].field.Get(res, lr)
+		if !ok {
+			val = pcommon.NewValueEmpty()
+		}
+		values = append(values, val)
+	}
+	return values
+}
+
+// Appends the resource values which are set as keys of the data object, hasKeys tells if the object has keys before them
+func AppendResourceJson(retSlice []byte, fields []DataField, values []pcommon.Value, hasKeys bool) []byte {
+	for i, val := range values {
+		if val.Type() == pcommon.ValueTypeEmpty {
+			continue
+		}
+		if hasKeys {
+			retSlice = append(retSlice, COMMA_BYTE)
+		}
+		retSlice = AppendJsonObjValue(fields[i].Key, val, retSlice)
+		hasKeys = true
+	}
+	return retSlice
+}
@@ -0,0 +1,65 @@
+package cloudevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestValidateResourceAttributes(t *testing.T) {
+	mappingCfg := &MappingConfig{IncludeAttributes: "attributes"}
+
+	assert.NoError(t, ValidateResourceAttributes(nil, mappingCfg))
+	assert.NoError(t, ValidateResourceAttributes([]ResourceAttributeConfig{
+		{From: "k8s.cluster.name", Key: "cluster"},
+		{From: "host.name"},
+		{From: "deployment.environment", Key: "env", To: RESOURCE_ATTR_TO_EXTENSION},
+	}, mappingCfg))
+
+	tests := map[string][]ResourceAttributeConfig{
+		"no from":               {{Key: "cluster"}},
+		"unknown to":            {{From: "k8s.cluster.name", To: "header"}},
+		"extension without key": {{From: "k8s.cluster.name", To: RESOURCE_ATTR_TO_EXTENSION}},
+		"mapped data key":       {{From: "k8s.cluster.name", Key: "reason"}},
+		"attributes key":        {{From: "k8s.cluster.name", Key: "attributes"}},
+		"duplicate":             {{From: "k8s.cluster.name"}, {From: "k8s.cluster.name"}},
+	}
+	for name, attrs := range tests {
+		assert.Error(t, ValidateResourceAttributes(attrs, mappingCfg), name)
+	}
+}
+
+func TestWithResourceExtensions(t *testing.T) {
+	extensions := []ExtensionConfig{{Name: "env", Value: "prod"}}
+	ret := WithResourceExtensions(extensions, []ResourceAttributeConfig{
+		{From: "k8s.cluster.name", Key: "cluster", To: RESOURCE_ATTR_TO_EXTENSION},
+		{From: "host.name"},
+	})
+
+	assert.Equal(t, []ExtensionConfig{
+		{Name: "env", Value: "prod"},
+		{Name: "cluster", From: "resource.k8s.cluster.name"},
+	}, ret)
+	assert.Len(t, extensions, 1)
+}
+
+func TestResourceJson(t *testing.T) {
+	res := pcommon.NewResource()
+	res.Attributes().PutStr("k8s.cluster.name", "east-1")
+	res.Attributes().PutStr("host.name", "node-1")
+	lr := plog.NewLogRecord()
+	lr.Attributes().PutStr("k8s.cluster.name", "not from the log")
+
+	fields := NewResourceDataFields([]ResourceAttributeConfig{
+		{From: "k8s.cluster.name", Key: "cluster"},
+		{From: "deployment.environment"},
+		{From: "host.name"},
+	})
+	values := appendResourceValues(nil, fields, res, lr)
+
+	assert.Equal(t, `"cluster":"east-1","host.name":"node-1"`, string(AppendResourceJson(nil, fields, values, false)))
+	assert.Equal(t, `,"cluster":"east-1","host.name":"node-1"`, string(AppendResourceJson(nil, fields, values, true)))
+	assert.Empty(t, AppendResourceJson(nil, fields, values[1:2], true))
+}
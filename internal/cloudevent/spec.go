@@ -0,0 +1,71 @@
+package cloudevent
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	// CloudEvents spec versions which can be produced
+	SPEC_VERSION_03 = "0.3"
+	SPEC_VERSION_10 = "1.0"
+
+	DATA_ENCODING_BASE64 = "base64"
+)
+
+/*
+SpecVersion has what differs between the spec versions, attribute names are the same in JSON and
+in the transport headers (with their prefix)
+*/
+type SpecVersion struct {
+	Version     string
+	TypeVersion string // segment in the default type, ex: `v1` in `com.company.event.v1.BackOff`
+
+	DataSchemaAttr   string // URI of the schema the data adheres to
+	DataEncodingAttr string // set to `base64` when the data is base64 encoded in JSON, empty if the spec doesn't have it
+	DataBase64Attr   string // attribute which carries base64 encoded data in JSON
+}
+
+var SpecVersions = map[string]*SpecVersion{
+	SPEC_VERSION_03: {
+		Version:          SPEC_VERSION_03,
+		TypeVersion:      "v03",
+		DataSchemaAttr:   "schemaurl",
+		DataEncodingAttr: "datacontentencoding",
+		DataBase64Attr:   "data",
+	},
+	SPEC_VERSION_10: {
+		Version:        SPEC_VERSION_10,
+		TypeVersion:    "v1",
+		DataSchemaAttr: "dataschema",
+		DataBase64Attr: "data_base64",
+	},
+}
+
+// Checks if the spec version can be produced, empty means the default 1.0
+func ValidateSpecVersion(version string) error {
+	if _, ok := SpecVersions[version]; !ok && len(version) > 0 {
+		return fmt.Errorf("spec_version should be one of '%s' or '%s', provided: %s", SPEC_VERSION_03, SPEC_VERSION_10, version)
+	}
+	return nil
+}
+
+// Returns the spec version, 1.0 if it's empty or not known
+func GetSpecVersion(version string) *SpecVersion {
+	if spec, ok := SpecVersions[version]; ok {
+		return spec
+	}
+	return SpecVersions[SPEC_VERSION_10]
+}
+
+// The data schema has to be an absolute URI, empty means it's not sent
+func ValidateDataSchema(schema string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if u, err := url.Parse(schema); err != nil || !u.IsAbs() {
+		return fmt.Errorf("data_schema should be an absolute URI, provided: %s", schema)
+	}
+	return nil
+}
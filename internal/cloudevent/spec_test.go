@@ -0,0 +1,28 @@
+package cloudevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSpecVersion(t *testing.T) {
+	for _, v := range []string{"", SPEC_VERSION_03, SPEC_VERSION_10} {
+		assert.NoError(t, ValidateSpecVersion(v), v)
+	}
+	for _, v := range []string{"1", "2.0", "v1", "1.0.2"} {
+		assert.Error(t, ValidateSpecVersion(v), v)
+	}
+
+	assert.Equal(t, "v1", GetSpecVersion("").TypeVersion)
+	assert.Equal(t, "schemaurl", GetSpecVersion(SPEC_VERSION_03).DataSchemaAttr)
+}
+
+func TestValidateDataSchema(t *testing.T) {
+	for _, v := range []string{"", "https://schemas.company.com/event.json", "urn:company:event:v1"} {
+		assert.NoError(t, ValidateDataSchema(v), v)
+	}
+	for _, v := range []string{"event.json", "/schemas/event.json", "http://[::1"} {
+		assert.Error(t, ValidateDataSchema(v), v)
+	}
+}
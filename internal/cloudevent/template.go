@@ -0,0 +1,313 @@
+package cloudevent
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// Layout of the CloudEvent type when nothing is configured, ex: `com.company.event.v1.BackOff`
+	DEFAULT_TYPE_TEMPLATE = `{{.AppendType}}.{{.TypeVersion}}.{{nospace .TypeSuffix}}`
+
+	// Subject of the CloudEvent when nothing is configured, ex: `testns/Pod/pod-1` or `Node/node-1` for
+	// cluster scoped objects, left out when the log isn't about a k8s object
+	DEFAULT_SUBJECT_TEMPLATE = `{{if .Attr "k8s.object.name"}}{{with .Attr "k8s.namespace.name"}}{{.}}/{{end}}{{.Attr "k8s.object.kind"}}/{{.Attr "k8s.object.name"}}{{end}}`
+)
+
+/*
+Functions available in the templates, all of them take a string and return a string
+Ex: `{{lower (.Attr "k8s.object.kind")}}` or `{{.TypeSuffix | kebab}}`
+*/
+var templateFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"kebab":   kebabCase,
+	"camel":   camelCase,
+	"nospace": removeSpaces,
+	"cesafe":  ceSafe,
+}
+
+/*
+TemplateContext is what the templates are executed on, it has the mapped fields of the log
+and methods to read any of its attributes
+*/
+type TemplateContext struct {
+	AppendType   string
+	TypeVersion  string
+	SpecVersion  string
+	Source       string
+	ID           string
+	Subject      string
+	TypeSuffix   string
+	SeverityText string
+
+	mapping  *Mapping
+	data     []pcommon.Value
+	resource pcommon.Resource
+	record   plog.LogRecord
+}
+
+// TypeBuilder forms the CloudEvent type of every log
+type TypeBuilder struct {
+	appendType  string
+	specVersion string
+	source      string
+	typeVersion string
+	tmpl        *template.Template // nil when the default layout is used, which doesn't need a template
+}
+
+// SubjectBuilder forms the CloudEvent subject of the logs which don't have it from mapping.subject
+type SubjectBuilder struct {
+	types *TypeBuilder       // gives the rest of the template context
+	tmpl  *template.Template // nil when the default layout is used
+}
+
+// Parses the template, name is used in the errors
+func ParseTemplate(name string, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid template: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+// Parses type_template, the other values are the same for every event and are available to the templates
+func NewTypeBuilder(typeTemplate string, appendType string, typeVersion string, specVersion string, source string) (*TypeBuilder, error) {
+	b := &TypeBuilder{
+		appendType:  appendType,
+		specVersion: specVersion,
+		source:      source,
+		typeVersion: typeVersion,
+	}
+
+	if len(typeTemplate) == 0 || typeTemplate == DEFAULT_TYPE_TEMPLATE {
+		return b, nil
+	}
+
+	var err error
+	b.tmpl, err = ParseTemplate("type_template", typeTemplate)
+	return b, err
+}
+
+// Forms the type of the CloudEvent using the mapped fields in ev and the log they came from
+func (b *TypeBuilder) Build(m *Mapping, res pcommon.Resource, lr plog.LogRecord, ev *Event) (string, error) {
+	if b.tmpl == nil {
+		return configureCeType(b.appendType, b.typeVersion, ev.TypeSuffix), nil
+	}
+
+	var ret strings.Builder
+	if err := b.tmpl.Execute(&ret, b.Context(m, res, lr, ev)); err != nil {
+		return "", err
+	}
+
+	return ret.String(), nil
+}
+
+func (b *TypeBuilder) Context(m *Mapping, res pcommon.Resource, lr plog.LogRecord, ev *Event) *TemplateContext {
+	return &TemplateContext{
+		AppendType:   b.appendType,
+		TypeVersion:  b.typeVersion,
+		SpecVersion:  b.specVersion,
+		Source:       b.source,
+		ID:           ev.ID,
+		Subject:      ev.Subject,
+		TypeSuffix:   ev.TypeSuffix,
+		SeverityText: lr.SeverityText(),
+		mapping:      m,
+		data:         ev.Data,
+		resource:     res,
+		record:       lr,
+	}
+}
+
+// Returns nil if subject_template is empty, the subject is left out then
+func NewSubjectBuilder(subjectTemplate string, types *TypeBuilder) (*SubjectBuilder, error) {
+	if len(subjectTemplate) == 0 {
+		return nil, nil
+	}
+
+	b := &SubjectBuilder{types: types}
+	if subjectTemplate == DEFAULT_SUBJECT_TEMPLATE {
+		return b, nil
+	}
+
+	var err error
+	b.tmpl, err = ParseTemplate("subject_template", subjectTemplate)
+	return b, err
+}
+
+// Forms the subject of the CloudEvent, empty if the template gives nothing
+func (b *SubjectBuilder) Build(m *Mapping, res pcommon.Resource, lr plog.LogRecord, ev *Event) (string, error) {
+	if b.tmpl == nil {
+		return k8sObjectSubject(res, lr), nil
+	}
+
+	var ret strings.Builder
+	if err := b.tmpl.Execute(&ret, b.types.Context(m, res, lr, ev)); err != nil {
+		return "", err
+	}
+
+	return ret.String(), nil
+}
+
+/*
+Function takes pretext from the params passed in config append_type and adds the reason to it
+Ex: append_type: `com.company.event` and reason: `Created Successfully`
+function will return `com.company.event.CreatedSuccessfully`
+*/
+func configureCeType(pretext string, typeVersion string, reason string) string {
+	var ret strings.Builder
+	ret.Grow(len(pretext) + len(reason))
+
+	ret.WriteString(pretext)
+	ret.WriteRune('.')
+	ret.WriteString(typeVersion) // It'll define the version
+	ret.WriteRune('.')
+
+	for _, ch := range reason {
+		if !unicode.IsSpace(ch) {
+			ret.WriteRune(ch)
+		}
+	}
+
+	return ret.String()
+}
+
+// RecordContext is the context of a log without its mapped fields, only the attributes can be read from it
+func RecordContext(res pcommon.Resource, lr plog.LogRecord) *TemplateContext {
+	return &TemplateContext{resource: res, record: lr}
+}
+
+// Same as DEFAULT_SUBJECT_TEMPLATE without executing the template
+func k8sObjectSubject(res pcommon.Resource, lr plog.LogRecord) string {
+	ctx := RecordContext(res, lr)
+
+	name := ctx.Attr(ATTR_OBJECT_NAME)
+	if len(name) == 0 {
+		return ""
+	}
+
+	kind := ctx.Attr(ATTR_OBJECT_KIND)
+	if ns := ctx.Attr(ATTR_EVENT_NS); len(ns) > 0 {
+		return ns + "/" + kind + "/" + name
+	}
+
+	return kind + "/" + name
+}
+
+// Attr returns the log attribute, or the resource attribute if the log doesn't have it
+func (c *TemplateContext) Attr(key string) string {
+	if val, ok := c.record.Attributes().Get(key); ok {
+		return val.AsString()
+	}
+	return c.ResourceAttr(key)
+}
+
+// ResourceAttr returns the resource attribute
+func (c *TemplateContext) ResourceAttr(key string) string {
+	if val, ok := c.resource.Attributes().Get(key); ok {
+		return val.AsString()
+	}
+	return ""
+}
+
+// Data returns the value of a key in mapping.data
+func (c *TemplateContext) Data(key string) string {
+	for i := range c.mapping.Data {
+		if c.mapping.Data[i].Key == key && i < len(c.data) {
+			return c.data[i].AsString()
+		}
+	}
+	return ""
+}
+
+// Splits `BackOff`, `back off`, `back_off` or `HTTPServer` into words
+func splitWords(str string) []string {
+	var words []string
+	runes := []rune(str)
+	start := -1
+
+	for i, ch := range runes {
+		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start >= 0 && unicode.IsUpper(ch) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// `BackOff` becomes `back-off`
+func kebabCase(str string) string {
+	words := splitWords(str)
+	for i := range words {
+		words[i] = strings.ToLower(words[i])
+	}
+	return strings.Join(words, "-")
+}
+
+// `back off` becomes `backOff`
+func camelCase(str string) string {
+	var ret strings.Builder
+
+	for i, w := range splitWords(str) {
+		w = strings.ToLower(w)
+		if i > 0 {
+			runes := []rune(w)
+			runes[0] = unicode.ToUpper(runes[0])
+			w = string(runes)
+		}
+		ret.WriteString(w)
+	}
+
+	return ret.String()
+}
+
+func removeSpaces(str string) string {
+	return strings.Map(func(ch rune) rune {
+		if unicode.IsSpace(ch) {
+			return -1
+		}
+		return ch
+	}, str)
+}
+
+/*
+Keeps only ASCII letters, digits, `.`, `-` and `_` so that the value can be used as is in
+CloudEvent types and in transport headers
+*/
+func ceSafe(str string) string {
+	return strings.Map(func(ch rune) rune {
+		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') ||
+			ch == '.' || ch == '-' || ch == '_' {
+			return ch
+		}
+		return -1
+	}, str)
+}
@@ -0,0 +1,132 @@
+package cloudevent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	assert.Equal(t, "back-off", kebabCase("BackOff"))
+	assert.Equal(t, "back-off", kebabCase("Back Off"))
+	assert.Equal(t, "http-server-error", kebabCase("HTTPServer_error"))
+	assert.Equal(t, "failed-to-pull-image2", kebabCase("failed to pull image2"))
+	assert.Equal(t, "backOff", camelCase("BackOff"))
+	assert.Equal(t, "failedCreatePodSandBox", camelCase("Failed create pod-sand-box"))
+	assert.Equal(t, "BackOff", removeSpaces(" Back\tOff "))
+	assert.Equal(t, "com.acme.pod-1_restart", ceSafe("com.acme.pod-1_restart/€ \""))
+}
+
+func TestTypeBuilder(t *testing.T) {
+	res := pcommon.NewResource()
+	res.Attributes().PutStr("k8s.object.kind", "Pod")
+	lr := plog.NewLogRecord()
+	lr.SetSeverityText("Warning")
+	lr.Attributes().PutStr(ATTR_EVENT_REASON, "Back Off")
+
+	m := NewMapping(&MappingConfig{ID: ATTR_EVENT_UID, TypeSuffix: ATTR_EVENT_REASON}, nil)
+	ev := &Event{}
+	m.Extract(res, lr, ev)
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{
+			template: "",
+			expected: "com.acme.k8s.v1.BackOff",
+		},
+		{
+			template: DEFAULT_TYPE_TEMPLATE,
+			expected: "com.acme.k8s.v1.BackOff",
+		},
+		{
+			template: `{{.AppendType}}.{{lower (.Attr "k8s.object.kind")}}.{{lower .SeverityText}}.{{nospace .TypeSuffix}}`,
+			expected: "com.acme.k8s.pod.warning.BackOff",
+		},
+		{
+			template: `{{.AppendType}}.{{.TypeSuffix | kebab}}.{{.Data "reason" | camel}}{{.ResourceAttr "missing"}}`,
+			expected: "com.acme.k8s.back-off.backOff",
+		},
+	}
+
+	for _, tt := range tests {
+		b, err := NewTypeBuilder(tt.template, "com.acme.k8s", "v1", "", "")
+		require.NoError(t, err)
+
+		typ, err := b.Build(m, res, lr, ev)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, typ)
+	}
+
+	_, err := NewTypeBuilder("{{.AppendType", "", "v1", "", "")
+	assert.Error(t, err)
+}
+
+func TestSubjectBuilder(t *testing.T) {
+	types, err := NewTypeBuilder("", "com.acme.k8s", "v1", "", "")
+	require.NoError(t, err)
+
+	// The default subject is formed without the template, check that both give the same
+	defaultTmpl, err := ParseTemplate("subject_template", DEFAULT_SUBJECT_TEMPLATE)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		attrs    map[string]string
+		expected string
+	}{
+		{name: "namespaced", attrs: map[string]string{ATTR_EVENT_NS: "testns", ATTR_OBJECT_KIND: "Pod", ATTR_OBJECT_NAME: "pod-1"}, expected: "testns/Pod/pod-1"},
+		{name: "cluster scoped", attrs: map[string]string{ATTR_OBJECT_KIND: "Node", ATTR_OBJECT_NAME: "node-1"}, expected: "Node/node-1"},
+		{name: "not an object", attrs: map[string]string{ATTR_EVENT_NS: "testns"}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := pcommon.NewResource()
+			lr := plog.NewLogRecord()
+			for k, v := range tt.attrs {
+				// Object fields are resource attributes in k8seventsreceiver
+				if k == ATTR_EVENT_NS {
+					lr.Attributes().PutStr(k, v)
+				} else {
+					res.Attributes().PutStr(k, v)
+				}
+			}
+
+			m := NewMapping(&MappingConfig{ID: ATTR_EVENT_UID, TypeSuffix: ATTR_EVENT_REASON}, nil)
+			ev := &Event{}
+			m.Extract(res, lr, ev)
+
+			b, err := NewSubjectBuilder(DEFAULT_SUBJECT_TEMPLATE, types)
+			require.NoError(t, err)
+			subject, err := b.Build(m, res, lr, ev)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, subject)
+
+			b.tmpl = defaultTmpl
+			subject, err = b.Build(m, res, lr, ev)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, subject)
+		})
+	}
+
+	b, err := NewSubjectBuilder(`{{lower (.Attr "k8s.object.kind")}}s/{{.Attr "k8s.object.name"}}`, types)
+	require.NoError(t, err)
+	res := pcommon.NewResource()
+	res.Attributes().PutStr(ATTR_OBJECT_KIND, "Pod")
+	res.Attributes().PutStr(ATTR_OBJECT_NAME, "pod-1")
+	subject, err := b.Build(nil, res, plog.NewLogRecord(), &Event{})
+	require.NoError(t, err)
+	assert.Equal(t, "pods/pod-1", subject)
+
+	b, err = NewSubjectBuilder("", types)
+	require.NoError(t, err)
+	assert.Nil(t, b)
+
+	_, err = NewSubjectBuilder("{{.Attr", types)
+	assert.Error(t, err)
+}
@@ -0,0 +1,225 @@
+package cloudevent
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// Where the CloudEvent time is taken from
+	TIME_SOURCE_MAPPING            = "mapping"            // field configured in mapping.time (k8s.event.start_time by default)
+	TIME_SOURCE_TIMESTAMP          = "timestamp"          // Timestamp of the log
+	TIME_SOURCE_OBSERVED_TIMESTAMP = "observed_timestamp" // ObservedTimestamp of the log
+
+	// What to do when the time can't be parsed, a missing time is always left out
+	INVALID_TIME_OMIT = "omit" // leave the time out of the CloudEvent
+	INVALID_TIME_NOW  = "now"  // use the current time
+	INVALID_TIME_FAIL = "fail" // fail the batch
+)
+
+/*
+Layouts of the timestamps which are seen in logs, k8seventsreceiver gives Go's time.String() format
+and filelog can give pretty much anything. Layouts without zone are considered UTC
+*/
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RubyDate,
+	time.UnixDate,
+}
+
+type TimeResolver struct {
+	field     *FieldRef // used for TIME_SOURCE_MAPPING, nil when mapping.time is empty
+	onInvalid string
+	source    string
+}
+
+// Checks the time configuration
+func ValidateTimeConfig(source string, onInvalid string) error {
+	switch source {
+	case "", TIME_SOURCE_MAPPING, TIME_SOURCE_TIMESTAMP, TIME_SOURCE_OBSERVED_TIMESTAMP:
+	default:
+		return fmt.Errorf("time_source should be one of '%s', '%s' or '%s', provided: %s",
+			TIME_SOURCE_MAPPING, TIME_SOURCE_TIMESTAMP, TIME_SOURCE_OBSERVED_TIMESTAMP, source)
+	}
+
+	switch onInvalid {
+	case "", INVALID_TIME_OMIT, INVALID_TIME_NOW, INVALID_TIME_FAIL:
+	default:
+		return fmt.Errorf("on_invalid_time should be one of '%s', '%s' or '%s', provided: %s",
+			INVALID_TIME_OMIT, INVALID_TIME_NOW, INVALID_TIME_FAIL, onInvalid)
+	}
+
+	return nil
+}
+
+// Source and onInvalid are time_source and on_invalid_time of the configuration, empty for the defaults
+func NewTimeResolver(source string, onInvalid string, m *Mapping) *TimeResolver {
+	r := &TimeResolver{
+		field:     m.time,
+		onInvalid: onInvalid,
+		source:    source,
+	}
+
+	if len(r.source) == 0 {
+		r.source = TIME_SOURCE_MAPPING
+	}
+
+	if len(r.onInvalid) == 0 {
+		r.onInvalid = INVALID_TIME_OMIT
+	}
+
+	return r
+}
+
+/*
+Returns the time of the log in RFC 3339 format, empty if the log doesn't have it
+An error is returned only if on_invalid_time is `fail` and the time couldn't be parsed
+*/
+func (r *TimeResolver) Resolve(res pcommon.Resource, lr plog.LogRecord) (string, error) {
+	var t time.Time
+
+	switch r.source {
+	case TIME_SOURCE_TIMESTAMP:
+		if lr.Timestamp() == 0 {
+			return "", nil
+		}
+		t = lr.Timestamp().AsTime()
+	case TIME_SOURCE_OBSERVED_TIMESTAMP:
+		if lr.ObservedTimestamp() == 0 {
+			return "", nil
+		}
+		t = lr.ObservedTimestamp().AsTime()
+	default:
+		if r.field == nil {
+			return "", nil
+		}
+
+		val, found := r.field.Get(res, lr)
+		if !found {
+			return "", nil
+		}
+
+		var ok bool
+		if t, ok = parseTime(val); !ok {
+			switch r.onInvalid {
+			case INVALID_TIME_NOW:
+				t = time.Now()
+			case INVALID_TIME_FAIL:
+				return "", fmt.Errorf("Couldn't parse time '%s' from {%s}", val.AsString(), r.field.name)
+			default:
+				return "", nil
+			}
+		}
+	}
+
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
+
+/*
+Parses the different formats a timestamp can come in. Numbers are taken as unix time, the unit
+(seconds, milliseconds, microseconds or nanoseconds) is guessed from the magnitude
+*/
+func parseTime(val pcommon.Value) (time.Time, bool) {
+	switch val.Type() {
+	case pcommon.ValueTypeInt:
+		return unixTime(val.Int()), true
+	case pcommon.ValueTypeDouble:
+		f := val.Double()
+		if math.IsNaN(f) || math.Abs(f) >= math.MaxInt64 {
+			return time.Time{}, false
+		}
+		// The fraction is rounded to microseconds as float64 can't keep nanoseconds of the current time
+		whole, frac := math.Modf(f)
+		unit := unixUnit(int64(whole))
+		return unixTimeIn(unit, int64(whole), int64(math.Round(frac*float64(unit)/1e3))*1e3), true
+	case pcommon.ValueTypeStr:
+		return parseTimeStr(val.Str())
+	}
+
+	return time.Time{}, false
+}
+
+func parseTimeStr(str string) (time.Time, bool) {
+	str = strings.TrimSpace(str)
+	if len(str) == 0 {
+		return time.Time{}, false
+	}
+
+	// Go's time.String() adds the monotonic clock reading at the end, ex: `m=+0.000123`
+	if idx := strings.Index(str, " m="); idx > 0 {
+		str = str[:idx]
+	}
+
+	if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return unixTime(i), true
+	}
+
+	// Unix time with fraction, parsed in two parts as float64 doesn't have the precision for nanoseconds
+	if dot := strings.IndexByte(str, '.'); dot > 0 && dot < len(str)-1 && len(str)-dot-1 <= 9 {
+		whole, wholeErr := strconv.ParseInt(str[:dot], 10, 64)
+		frac := str[dot+1:] + strings.Repeat("0", 9-(len(str)-dot-1))
+		nanos, fracErr := strconv.ParseUint(frac, 10, 64)
+		if wholeErr == nil && fracErr == nil {
+			// Billionths of the unit to nanoseconds
+			unit := unixUnit(whole)
+			nsec := int64(nanos) * unit / 1e9
+			// The fraction has the sign of the number, ex: `-1.5` is 1.5s before the epoch and `-0.5` isn't 0.5s after it
+			if str[0] == '-' {
+				nsec = -nsec
+			}
+			return unixTimeIn(unit, whole, nsec), true
+		}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func unixTime(i int64) time.Time {
+	return unixTimeIn(unixUnit(i), i, 0)
+}
+
+// Nanoseconds in the unit of a unix time, guessed from its magnitude like 1e11 seconds is year 5138
+func unixUnit(i int64) int64 {
+	abs := i
+	if abs < 0 {
+		abs = -abs
+	}
+
+	switch {
+	case abs < 1e11:
+		return int64(time.Second)
+	case abs < 1e14:
+		return int64(time.Millisecond)
+	case abs < 1e17:
+		return int64(time.Microsecond)
+	}
+
+	return int64(time.Nanosecond)
+}
+
+// Time which is whole units and nsec nanoseconds from the epoch, split in seconds so that it doesn't overflow
+func unixTimeIn(unit int64, whole int64, nsec int64) time.Time {
+	perSec := int64(time.Second) / unit
+	return time.Unix(whole/perSec, whole%perSec*unit+nsec)
+}
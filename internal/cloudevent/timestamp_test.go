@@ -0,0 +1,73 @@
+package cloudevent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestParseTime(t *testing.T) {
+	expected := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	expectedMilli := expected.Add(123 * time.Millisecond)
+
+	valid := []struct {
+		val      pcommon.Value
+		expected time.Time
+	}{
+		{pcommon.NewValueStr("2023-03-01T10:00:00Z"), expected},
+		{pcommon.NewValueStr("2023-03-01T11:00:00.123+01:00"), expectedMilli},
+		{pcommon.NewValueStr("2023-03-01T10:00:00.123+0000"), expectedMilli},
+		{pcommon.NewValueStr("2023-03-01 10:00:00 +0000 UTC"), expected},
+		{pcommon.NewValueStr("2023-03-01 10:00:00.123 +0000 UTC m=+0.000012"), expectedMilli},
+		{pcommon.NewValueStr("2023-03-01 10:00:00.123"), expectedMilli},
+		{pcommon.NewValueStr("2023-03-01T10:00:00"), expected},
+		{pcommon.NewValueStr("01/Mar/2023:10:00:00 +0000"), expected},
+		{pcommon.NewValueStr("Wed, 01 Mar 2023 10:00:00 +0000"), expected},
+		{pcommon.NewValueStr(" 1677664800 "), expected},
+		{pcommon.NewValueStr("1677664800.123"), expectedMilli},
+		{pcommon.NewValueStr("-1.5"), time.Unix(-2, 5e8)},
+		{pcommon.NewValueStr("-0.5"), time.Unix(0, -5e8)},
+		{pcommon.NewValueDouble(-1.5), time.Unix(-2, 5e8)},
+		{pcommon.NewValueInt(1677664800), expected},
+		{pcommon.NewValueInt(1677664800123), expectedMilli},
+		{pcommon.NewValueInt(1677664800123000), expectedMilli},
+		{pcommon.NewValueInt(1677664800123000000), expectedMilli},
+		{pcommon.NewValueDouble(1677664800), expected},
+		{pcommon.NewValueDouble(1677664800.123), expectedMilli},
+		{pcommon.NewValueDouble(1677664800123), expectedMilli},
+		{pcommon.NewValueDouble(1677664800122.5), expectedMilli.Add(-500 * time.Microsecond)},
+		{pcommon.NewValueDouble(1677664800123000), expectedMilli},
+		{pcommon.NewValueDouble(1677664800122999), expectedMilli.Add(-time.Microsecond)},
+		{pcommon.NewValueDouble(-1677664800123.5), time.UnixMicro(-1677664800123500)},
+		{pcommon.NewValueStr("1677664800122.5"), expectedMilli.Add(-500 * time.Microsecond)},
+		{pcommon.NewValueStr("1677664800122999.5"), expectedMilli.Add(-500 * time.Nanosecond)},
+		{pcommon.NewValueStr("-1677664800123.5"), time.UnixMicro(-1677664800123500)},
+	}
+
+	for _, tt := range valid {
+		actual, ok := parseTime(tt.val)
+		assert.True(t, ok, tt.val.AsString())
+		assert.True(t, tt.expected.Equal(actual), "%s parsed as %s", tt.val.AsString(), actual)
+	}
+
+	invalid := []pcommon.Value{
+		pcommon.NewValueStr(""),
+		pcommon.NewValueStr("yesterday"),
+		pcommon.NewValueBool(true),
+		pcommon.NewValueEmpty(),
+	}
+
+	for _, val := range invalid {
+		_, ok := parseTime(val)
+		assert.False(t, ok, val.AsString())
+	}
+}
+
+func TestValidateTimeConfig(t *testing.T) {
+	assert.NoError(t, ValidateTimeConfig("", ""))
+	assert.NoError(t, ValidateTimeConfig(TIME_SOURCE_OBSERVED_TIMESTAMP, INVALID_TIME_NOW))
+	assert.Error(t, ValidateTimeConfig("start_time", ""))
+	assert.Error(t, ValidateTimeConfig("", "skip"))
+}
@@ -0,0 +1,31 @@
+package cloudevent
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// Attributes of the CloudEvents distributed tracing extension, log records don't carry a tracestate
+	// so only traceparent is written
+	EXTENSION_TRACEPARENT = "traceparent"
+	EXTENSION_TRACESTATE  = "tracestate"
+
+	TRACEPARENT_VERSION = "00"
+)
+
+/*
+Returns the W3C traceparent of the log record, ex: `00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01`
+Empty if the record doesn't have a trace context
+*/
+func TraceParent(lr plog.LogRecord) string {
+	if lr.TraceID().IsEmpty() || lr.SpanID().IsEmpty() {
+		return ""
+	}
+
+	flags := "00"
+	if lr.Flags().IsSampled() {
+		flags = "01"
+	}
+
+	return TRACEPARENT_VERSION + "-" + lr.TraceID().String() + "-" + lr.SpanID().String() + "-" + flags
+}
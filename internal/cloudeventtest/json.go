@@ -0,0 +1,65 @@
+/*
+Package cloudeventtest has the helpers which the tests of the processor and the exporter share, strings which
+are hard to escape and a decoder of protobuf CloudEvents
+*/
+package cloudeventtest
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// NastyStrings are inputs which broke the JSON earlier or are known to be hard to get right
+var NastyStrings = []string{
+	"",
+	"plain",
+	`say "hi"`,
+	`C:\Users\test\`,
+	`\"already escaped\"`,
+	"line1\nline2\r\nline3",
+	"tab\there",
+	"\b\f\v\x00\x01\x1f\x7f",
+	"Back-off restarting failed container\n\tat main.go:42",
+	`{"looks":"like json"}`,
+	"</script><script>alert(1)</script>&amp;",
+	"line\u2028separator\u2029paragraph",
+	"emoji 🚀 and ünïcödé and 日本語",
+	"invalid \xff utf8",
+	"truncated \xe2\x82",
+	"surrogate \xed\xa0\x80 half",
+	"overlong \xc0\xaf slash",
+	"\xf4\x90\x80\x80 out of range",
+	strings.Repeat(`"\`, 100),
+}
+
+// JsonTestCorpus is NastyStrings, every control character and a few random strings made of bytes, most of them invalid UTF-8
+func JsonTestCorpus() []string {
+	corpus := append([]string{}, NastyStrings...)
+
+	for ch := 0; ch < 0x20; ch++ {
+		corpus = append(corpus, "a"+string(rune(ch))+"b")
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		b := make([]byte, rnd.Intn(32))
+		rnd.Read(b)
+		corpus = append(corpus, string(b))
+	}
+
+	return corpus
+}
+
+// ExpectedJsonStr is what encoding/json gives after a round trip, invalid UTF-8 is replaced by U+FFFD
+func ExpectedJsonStr(t *testing.T, str string) string {
+	b, err := json.Marshal(str)
+	require.NoError(t, err)
+
+	var ret string
+	require.NoError(t, json.Unmarshal(b, &ret))
+	return ret
+}
@@ -0,0 +1,166 @@
+package cloudeventtest
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+/*
+ProtoEvent is a CloudEvent message decoded with protowire, only the fields which are encoded are kept. The field numbers are
+written out as they are in cloudevents.proto of the spec rather than taken from the PROTO_FIELD_* constants,
+so that a wrong number in the encoder doesn't get decoded the same wrong way:
+
+	message CloudEvent {
+	  string id = 1;
+	  string source = 2;
+	  string spec_version = 3;
+	  string type = 4;
+	  map<string, CloudEventAttributeValue> attributes = 5;
+	  oneof data {
+	    bytes binary_data = 6;
+	    string text_data = 7;
+	    google.protobuf.Any proto_data = 8;
+	  }
+	}
+
+	message CloudEventAttributeValue {
+	  oneof attr {
+	    bool ce_boolean = 1;
+	    int32 ce_integer = 2;
+	    string ce_string = 3;
+	    bytes ce_bytes = 4;
+	    string ce_uri = 5;
+	    string ce_uri_ref = 6;
+	    google.protobuf.Timestamp ce_timestamp = 7;
+	  }
+	}
+
+	message CloudEventBatch {
+	  repeated CloudEvent events = 1;
+	}
+*/
+type ProtoEvent struct {
+	ID          string
+	Source      string
+	SpecVersion string
+	Type        string
+	Attributes  map[string]ProtoAttribute
+	BinaryData  []byte
+	TextData    string
+}
+
+// ProtoAttribute is a CloudEventAttributeValue, only one of them is set
+type ProtoAttribute struct {
+	Str       string // ce_string
+	URI       string // ce_uri
+	Timestamp *time.Time
+}
+
+// Calls f with every field of the message, val is the value of bytes fields and v of varint fields
+func consumeProtoFields(t *testing.T, b []byte, f func(num protowire.Number, val []byte, v uint64)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0, "invalid tag")
+		b = b[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			val, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0, "invalid length of field %d", num)
+			f(num, val, 0)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0, "invalid varint of field %d", num)
+			f(num, nil, v)
+			b = b[n:]
+		default:
+			t.Fatalf("field %d has unexpected wire type %d", num, typ)
+		}
+	}
+}
+
+// Strings have to be valid UTF-8 in protobuf
+func protoString(t *testing.T, val []byte) string {
+	require.True(t, utf8.Valid(val), "string field isn't valid UTF-8: %q", val)
+	return string(val)
+}
+
+// UnmarshalProtoEvent decodes a CloudEvent, the test fails if it has a field which the encoder never writes
+func UnmarshalProtoEvent(t *testing.T, b []byte) ProtoEvent {
+	event := ProtoEvent{Attributes: map[string]ProtoAttribute{}}
+	consumeProtoFields(t, b, func(num protowire.Number, val []byte, _ uint64) {
+		switch num {
+		case 1: // id
+			event.ID = protoString(t, val)
+		case 2: // source
+			event.Source = protoString(t, val)
+		case 3: // spec_version
+			event.SpecVersion = protoString(t, val)
+		case 4: // type
+			event.Type = protoString(t, val)
+		case 5: // attributes
+			name, attr := unmarshalProtoAttribute(t, val)
+			event.Attributes[name] = attr
+		case 6: // binary_data
+			event.BinaryData = val
+		case 7: // text_data
+			event.TextData = protoString(t, val)
+		default:
+			t.Fatalf("unexpected field %d in CloudEvent", num)
+		}
+	})
+	return event
+}
+
+// Entry of CloudEvent.attributes, a map entry is a message of key = 1 and value = 2
+func unmarshalProtoAttribute(t *testing.T, b []byte) (string, ProtoAttribute) {
+	var name string
+	var attr ProtoAttribute
+	consumeProtoFields(t, b, func(num protowire.Number, val []byte, _ uint64) {
+		switch num {
+		case 1:
+			name = protoString(t, val)
+		case 2:
+			consumeProtoFields(t, val, func(kind protowire.Number, val []byte, _ uint64) {
+				switch kind {
+				case 3: // ce_string
+					attr.Str = protoString(t, val)
+				case 5: // ce_uri
+					attr.URI = protoString(t, val)
+				case 7: // ce_timestamp, google.protobuf.Timestamp is seconds = 1 and nanos = 2
+					var secs, nanos int64
+					consumeProtoFields(t, val, func(num protowire.Number, _ []byte, v uint64) {
+						switch num {
+						case 1:
+							secs = int64(v)
+						case 2:
+							nanos = int64(int32(v))
+						}
+					})
+					ts := time.Unix(secs, nanos).UTC()
+					attr.Timestamp = &ts
+				default:
+					t.Fatalf("unexpected kind %d of attribute", kind)
+				}
+			})
+		default:
+			t.Fatalf("unexpected field %d in attributes entry", num)
+		}
+	})
+	return name, attr
+}
+
+// UnmarshalProtoBatch decodes a CloudEventBatch, `repeated CloudEvent events = 1`
+func UnmarshalProtoBatch(t *testing.T, b []byte) []ProtoEvent {
+	var events []ProtoEvent
+	consumeProtoFields(t, b, func(num protowire.Number, val []byte, _ uint64) {
+		require.Equal(t, protowire.Number(1), num)
+		events = append(events, UnmarshalProtoEvent(t, val))
+	})
+	return events
+}